@@ -26,6 +26,7 @@ import (
 	"github.com/joho/godotenv"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -108,6 +109,15 @@ func CatchCLI(args []string) error {
 	case "help":
 		HelpEcho()
 		return errors.New("end")
+	case "probe":
+		endpoint := os.Getenv("PROBE_ENDPOINT")
+		location := os.Getenv("PROBE_LOCATION")
+		if endpoint == "" || location == "" {
+			return errors.New("PROBE_ENDPOINT and PROBE_LOCATION environment variables are required to run a probe")
+		}
+		core.Probe = core.NewProber(endpoint, os.Getenv("PROBE_SECRET"), location)
+		utils.Log(1, fmt.Sprintf("Running Statup probe %v for %v", location, endpoint))
+		core.Probe.Run()
 	case "run":
 		utils.Log(1, "Running 1 time and saving to database...")
 		RunOnce()
@@ -163,6 +173,7 @@ func HelpEcho() {
 	fmt.Println("     statup                    - Main command to run Statup server")
 	fmt.Println("     statup version            - Returns the current version of Statup")
 	fmt.Println("     statup run                - Check all services 1 time and then quit")
+	fmt.Println("     statup probe              - Check services from PROBE_ENDPOINT and send results as PROBE_LOCATION")
 	fmt.Println("     statup test plugins       - Test all plugins for required information")
 	fmt.Println("     statup assets             - Dump all assets used locally to be edited.")
 	fmt.Println("     statup sass               - Compile .scss files into the css directory")
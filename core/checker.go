@@ -17,24 +17,40 @@ package core
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/hunterlong/statup/core/notifier"
 	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// defaultTimeout is set by normalize on services that don't have a timeout
+const defaultTimeout = 30 * time.Second
+
 // checkServices will start the checking go routine for each service
 func checkServices() {
 	utils.Log(1, fmt.Sprintf("Starting monitoring process for %v Services", len(CoreApp.Services)))
 	for _, ser := range CoreApp.Services {
-		//go obj.StartCheckins()
+		ser.(*Service).startCheckins()
 		go ser.CheckQueue(true)
 	}
 }
@@ -50,6 +66,11 @@ CheckLoop:
 			utils.Log(1, fmt.Sprintf("Stopping service: %v", s.Name))
 			break CheckLoop
 		case <-time.After(s.SleepDuration):
+			if s.Paused {
+				s.Checkpoint = time.Now()
+				s.SleepDuration = s.duration()
+				continue
+			}
 			s.Check(record)
 			s.Checkpoint = s.Checkpoint.Add(s.duration())
 			sleep := s.Checkpoint.Sub(time.Now())
@@ -125,6 +146,322 @@ func (s *Service) checkTcp(record bool) *Service {
 	return s
 }
 
+// checkGrpc will check a gRPC service with the grpc.health.v1.Health Check method, any status but SERVING will fail
+func (s *Service) checkGrpc(record bool) *Service {
+	t1 := time.Now()
+	domain := fmt.Sprintf("%v", s.Domain)
+	if s.Port != 0 {
+		domain = fmt.Sprintf("%v:%v", s.Domain, s.Port)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout)*time.Second)
+	defer cancel()
+	transport := grpc.WithInsecure()
+	if s.GrpcTls {
+		transport = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: s.SkipVerify}))
+	}
+	conn, err := grpc.DialContext(ctx, domain, transport)
+	if err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("GRPC Dial Error %v", err))
+		}
+		return s
+	}
+	defer conn.Close()
+	res, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: s.GrpcService})
+	if err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("GRPC Health Check Error %v", err))
+		}
+		return s
+	}
+	t2 := time.Now()
+	s.Latency = t2.Sub(t1).Seconds()
+	s.LastResponse = res.Status.String()
+	if res.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		if record {
+			recordFailure(s, fmt.Sprintf("GRPC Health Status %v is not SERVING", res.Status))
+		}
+		return s
+	}
+	if record {
+		recordSuccess(s)
+	}
+	return s
+}
+
+// checkUdp will check a UDP service by sending the PostData payload and matching the reply with the Expected regex
+func (s *Service) checkUdp(record bool) *Service {
+	t1 := time.Now()
+	domain := fmt.Sprintf("%v", s.Domain)
+	if s.Port != 0 {
+		domain = fmt.Sprintf("%v:%v", s.Domain, s.Port)
+	}
+	timeout := time.Duration(s.Timeout) * time.Second
+	conn, err := net.DialTimeout("udp", domain, timeout)
+	if err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("UDP Dial Error %v", err))
+		}
+		return s
+	}
+	defer conn.Close()
+	conn.SetDeadline(t1.Add(timeout))
+	if _, err := conn.Write(udpPayload(s.PostData)); err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("UDP Write Error %v", err))
+		}
+		return s
+	}
+	reply := make([]byte, 65535)
+	n, err := conn.Read(reply)
+	if err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("UDP Read Error %v", err))
+		}
+		return s
+	}
+	s.Latency = time.Now().Sub(t1).Seconds()
+	s.LastResponse = string(reply[:n])
+	if s.Expected != "" {
+		match, err := regexp.MatchString(s.Expected, s.LastResponse)
+		if err != nil {
+			utils.Log(2, err)
+		}
+		if !match {
+			if record {
+				recordFailure(s, fmt.Sprintf("UDP Response did not match '%v'", s.Expected))
+			}
+			return s
+		}
+	}
+	if record {
+		recordSuccess(s)
+	}
+	return s
+}
+
+// udpPayload returns the bytes to send for a UDP check, escape sequences such as \x00 or \n will be decoded
+// so binary payloads can be sent. If the payload cannot be decoded it will be sent as is.
+func udpPayload(data string) []byte {
+	decoded, err := strconv.Unquote(`"` + data + `"`)
+	if err != nil {
+		return []byte(data)
+	}
+	return []byte(decoded)
+}
+
+// checkDns will query the DNS resolver for the service's record type and match the answers with the Expected regex
+func (s *Service) checkDns(record bool) *Service {
+	t1 := time.Now()
+	answers, err := s.dnsRecords()
+	if err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("DNS %v Lookup Error %v", s.DnsRecord, err))
+		}
+		return s
+	}
+	s.Latency = time.Now().Sub(t1).Seconds()
+	s.LastResponse = strings.Join(answers, "\n")
+	if len(answers) == 0 {
+		if record {
+			recordFailure(s, fmt.Sprintf("DNS %v Lookup for %v returned no records", s.DnsRecord, s.Domain))
+		}
+		return s
+	}
+	if s.Expected != "" {
+		var match bool
+		for _, answer := range answers {
+			match, err = regexp.MatchString(s.Expected, answer)
+			if err != nil {
+				utils.Log(2, err)
+			}
+			if match {
+				break
+			}
+		}
+		if !match {
+			if record {
+				recordFailure(s, fmt.Sprintf("DNS %v Records %v did not match '%v'", s.DnsRecord, answers, s.Expected))
+			}
+			return s
+		}
+	}
+	if record {
+		recordSuccess(s)
+	}
+	return s
+}
+
+// dnsRecords returns the answers for the service's DNS record type (A, AAAA, CNAME, MX or TXT). If DnsResolver is
+// set, the query will be sent to that resolver instead of the system's default.
+func (s *Service) dnsRecords() ([]string, error) {
+	timeout := time.Duration(s.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resolver := &net.Resolver{PreferGo: true}
+	if s.DnsResolver != "" {
+		server := s.DnsResolver
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		resolver.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: timeout}
+			return dialer.DialContext(ctx, network, server)
+		}
+	}
+	var answers []string
+	switch strings.ToUpper(s.DnsRecord) {
+	case "A", "AAAA", "":
+		addrs, err := resolver.LookupIPAddr(ctx, s.Domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			isV4 := addr.IP.To4() != nil
+			if isV4 == (strings.ToUpper(s.DnsRecord) != "AAAA") {
+				answers = append(answers, addr.IP.String())
+			}
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, s.Domain)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, s.Domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			answers = append(answers, fmt.Sprintf("%v %v", mx.Pref, mx.Host))
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, s.Domain)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, records...)
+	default:
+		return nil, fmt.Errorf("unsupported record type %v", s.DnsRecord)
+	}
+	return answers, nil
+}
+
+// checkIcmp will check a host by sending ICMP echo requests, the average round trip time is used as the latency
+func (s *Service) checkIcmp(record bool) *Service {
+	count := s.PacketCount
+	if count <= 0 {
+		count = 3
+	}
+	rtts, err := ping(s.Domain, count, time.Duration(s.Timeout)*time.Second)
+	if err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("ICMP Error %v", err))
+		}
+		return s
+	}
+	loss := float64(count-len(rtts)) / float64(count) * 100
+	if len(rtts) == 0 || loss > float64(s.MaxPacketLoss) {
+		if record {
+			recordFailure(s, fmt.Sprintf("ICMP Packet Loss %0.0f%% is greater than %v%%", loss, s.MaxPacketLoss))
+		}
+		return s
+	}
+	var total time.Duration
+	for _, rtt := range rtts {
+		total += rtt
+	}
+	s.Latency = (total / time.Duration(len(rtts))).Seconds()
+	s.LastResponse = ""
+	if record {
+		recordSuccess(s)
+	}
+	return s
+}
+
+// pingIds is incremented for each ping so concurrent checks don't accept each other's replies
+var pingIds uint32
+
+// ping will send ICMP echo requests to a host and return the round trip time for each reply received.
+// Unprivileged ICMP sockets are used when available, otherwise it will fallback to a raw socket.
+func ping(host string, count int, timeout time.Duration) ([]time.Duration, error) {
+	addr, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		return nil, err
+	}
+	network, rawNetwork, protocol := "udp4", "ip4:icmp", 1
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if addr.IP.To4() == nil {
+		network, rawNetwork, protocol = "udp6", "ip6:ipv6-icmp", 58
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	var dest net.Addr = &net.UDPAddr{IP: addr.IP, Zone: addr.Zone}
+	// unprivileged sockets only receive replies to their own requests, the kernel sets the echo ID to the
+	// socket's port. Raw sockets receive every reply, so the echo ID has to be matched.
+	raw := false
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		conn, err = icmp.ListenPacket(rawNetwork, "")
+		if err != nil {
+			return nil, err
+		}
+		dest = addr
+		raw = true
+	}
+	defer conn.Close()
+	id := int(atomic.AddUint32(&pingIds, 1)+uint32(os.Getpid())) & 0xffff
+
+	wait := timeout / time.Duration(count)
+	reply := make([]byte, 1500)
+	var rtts []time.Duration
+	for seq := 1; seq <= count; seq++ {
+		msg := icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("statup")},
+		}
+		data, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, err
+		}
+		sent := time.Now()
+		if _, err := conn.WriteTo(data, dest); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(sent.Add(wait))
+		for {
+			n, peer, err := conn.ReadFrom(reply)
+			if err != nil {
+				break
+			}
+			if !addr.IP.Equal(peerIP(peer)) {
+				continue
+			}
+			received, err := icmp.ParseMessage(protocol, reply[:n])
+			if err != nil || received.Type != replyType {
+				continue
+			}
+			echo, ok := received.Body.(*icmp.Echo)
+			if ok && echo.Seq == seq && (!raw || echo.ID == id) {
+				rtts = append(rtts, time.Since(sent))
+				break
+			}
+		}
+	}
+	return rtts, nil
+}
+
+// peerIP returns the IP address of the host that sent an ICMP reply
+func peerIP(peer net.Addr) net.IP {
+	switch a := peer.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	}
+	return nil
+}
+
 // checkHttp will check a HTTP service
 func (s *Service) checkHttp(record bool) *Service {
 	dnsLookup, err := s.dnsCheck()
@@ -135,38 +472,49 @@ func (s *Service) checkHttp(record bool) *Service {
 		return s
 	}
 	s.DnsLookup = dnsLookup
-	t1 := time.Now()
+	request, err := s.httpRequest()
+	if err != nil {
+		if record {
+			recordFailure(s, fmt.Sprintf("HTTP Request Error %v", err))
+		}
+		return s
+	}
 	timeout := time.Duration(s.Timeout)
 	client := http.Client{
 		Timeout: timeout * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: s.SkipVerify},
+		},
 	}
-
-	var response *http.Response
-	if s.Method == "POST" {
-		response, err = client.Post(s.Domain, "application/json", bytes.NewBuffer([]byte(s.PostData)))
-	} else {
-		response, err = client.Get(s.Domain)
+	if s.NoRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
 	}
+	t1 := time.Now()
+	timings := &httpTimings{start: t1}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), timings.trace()))
+	response, err := client.Do(request)
 	if err != nil {
 		if record {
 			recordFailure(s, fmt.Sprintf("HTTP Error %v", err))
 		}
 		return s
 	}
-	response.Header.Set("Connection", "close")
-	response.Header.Set("User-Agent", "StatupMonitor")
 	t2 := time.Now()
 	s.Latency = t2.Sub(t1).Seconds()
-	if err != nil {
-		if record {
-			recordFailure(s, fmt.Sprintf("HTTP Error %v", err))
-		}
-		return s
+	if timings.dns > 0 {
+		s.DnsLookup = timings.dns.Seconds()
 	}
+	s.ConnectTime = timings.connect.Seconds()
+	s.TLSHandshake = timings.tls.Seconds()
+	s.FirstByte = timings.firstByte.Seconds()
 	defer response.Body.Close()
 	contents, err := ioutil.ReadAll(response.Body)
 	s.LastResponse = string(contents)
 	s.LastStatusCode = response.StatusCode
+	s.tlsCertificate(response.TLS)
 
 	if s.Expected != "" {
 		if err != nil {
@@ -189,46 +537,316 @@ func (s *Service) checkHttp(record bool) *Service {
 		}
 		return s
 	}
-	s.Online = true
+	if s.CertExpiryWarn > 0 && !s.CertExpiry.IsZero() {
+		expiresIn := s.CertExpiry.Sub(time.Now())
+		if expiresIn < time.Duration(s.CertExpiryWarn)*24*time.Hour {
+			if record {
+				recordFailure(s, fmt.Sprintf("TLS Certificate expires in %0.0f days on %v", expiresIn.Hours()/24, s.CertExpiry.UTC().Format(types.TIME)))
+			}
+			return s
+		}
+	}
 	if record {
 		recordSuccess(s)
 	}
 	return s
 }
 
-// Check will run checkHttp for HTTP services and checkTcp for TCP services
+// httpTimings is the time spent in each phase of a HTTP check, added up over any redirects
+type httpTimings struct {
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	dns          time.Duration
+	connect      time.Duration
+	tls          time.Duration
+	firstByte    time.Duration
+}
+
+// trace returns a httptrace.ClientTrace that will record the timings of a request. The time to first byte
+// is measured from the start of the check to the first byte of the last response.
+func (h *httpTimings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			h.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			h.dns += time.Now().Sub(h.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			h.connectStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			h.connect += time.Now().Sub(h.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			h.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			h.tls += time.Now().Sub(h.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			h.firstByte = time.Now().Sub(h.start)
+		},
+	}
+}
+
+// httpRequest will create the HTTP request for the service with its method, body, content type and headers
+func (s *Service) httpRequest() (*http.Request, error) {
+	method := strings.ToUpper(s.Method)
+	if method == "" {
+		method = "GET"
+	}
+	var body io.Reader
+	if s.PostData != "" && method != "GET" && method != "HEAD" {
+		body = bytes.NewBufferString(s.PostData)
+	}
+	request, err := http.NewRequest(method, s.Domain, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Close = true
+	request.Header.Set("User-Agent", "StatupMonitor")
+	if body != nil {
+		contentType := s.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		request.Header.Set("Content-Type", contentType)
+	}
+	for _, line := range strings.Split(s.Headers, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if strings.EqualFold(key, "Host") {
+			request.Host = value
+			continue
+		}
+		request.Header.Set(key, value)
+	}
+	return request, nil
+}
+
+// tlsCertificate will save the expiry date of the certificate chain, along with the issuer and SANs of the leaf certificate.
+// They're cleared when the response wasn't sent over TLS, so a certificate from an earlier check doesn't stay expiring.
+func (s *Service) tlsCertificate(state *tls.ConnectionState) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		s.CertExpiry = time.Time{}
+		s.CertIssuer = ""
+		s.CertDNSNames = nil
+		return
+	}
+	leaf := state.PeerCertificates[0]
+	expiry := leaf.NotAfter
+	for _, cert := range state.PeerCertificates[1:] {
+		if cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	s.CertExpiry = expiry
+	s.CertIssuer = leaf.Issuer.String()
+	s.CertDNSNames = leaf.DNSNames
+}
+
+// Check will run the check function for the service's type, such as checkHttp for HTTP services. A failing
+// check will be retried up to Retries times before the failure is recorded, waiting RetryDelay milliseconds
+// before the first retry and doubling the delay for each retry after that.
 func (s *Service) Check(record bool) {
+	delay := time.Duration(s.RetryDelay) * time.Millisecond
+	for s.Attempt = 1; ; s.Attempt++ {
+		s.Retrying = false
+		s.runCheck(record)
+		if !s.Retrying {
+			return
+		}
+		time.Sleep(delay)
+		delay = delay * 2
+	}
+}
+
+// runCheck will run a single attempt of the check function for the service's type
+func (s *Service) runCheck(record bool) {
 	switch s.Type {
 	case "http":
 		s.checkHttp(record)
 	case "tcp":
 		s.checkTcp(record)
+	case "udp":
+		s.checkUdp(record)
+	case "grpc":
+		s.checkGrpc(record)
+	case "dns":
+		s.checkDns(record)
+	case "icmp":
+		s.checkIcmp(record)
 	}
 }
 
-// recordSuccess will create a new 'hit' record in the database for a successful/online service
+// recordSuccess will create a new 'hit' record in the database for a successful/online service. When Statup
+// is running as a probe, the result will be sent to the main Statup instance instead.
 func recordSuccess(s *Service) {
-	s.Online = true
-	s.LastOnline = time.Now()
-	hit := &types.Hit{
-		Service:   s.Id,
-		Latency:   s.Latency,
-		CreatedAt: time.Now(),
+	if Probe != nil {
+		Probe.Report(s, &types.ProbeResult{
+			Online:       true,
+			Latency:      s.Latency,
+			DnsLookup:    s.DnsLookup,
+			ConnectTime:  s.ConnectTime,
+			TLSHandshake: s.TLSHandshake,
+			FirstByte:    s.FirstByte,
+		})
+		return
 	}
-	utils.Log(1, fmt.Sprintf("Service %v Successful: %0.2f ms", s.Name, hit.Latency*1000))
-	s.CreateHit(hit)
-	notifier.OnSuccess(s.Service)
+	s.addHit(&types.Hit{
+		Service:      s.Id,
+		Latency:      s.Latency,
+		DnsLookup:    s.DnsLookup,
+		ConnectTime:  s.ConnectTime,
+		TLSHandshake: s.TLSHandshake,
+		FirstByte:    s.FirstByte,
+		CreatedAt:    time.Now(),
+	})
 }
 
-// recordFailure will create a new 'failure' record in the database for a offline service
+// recordFailure will create a new 'failure' record in the database for a offline service. When Statup is
+// running as a probe, the result will be sent to the main Statup instance instead.
 func recordFailure(s *Service, issue string) {
-	s.Online = false
-	fail := &types.Failure{
+	if s.Attempt > 0 && s.Attempt <= s.Retries {
+		utils.Log(1, fmt.Sprintf("Service %v Attempt %v/%v Failed: %v", s.Name, s.Attempt, s.Retries+1, issue))
+		s.Retrying = true
+		return
+	}
+	if Probe != nil {
+		Probe.Report(s, &types.ProbeResult{Online: false, Latency: s.Latency, Issue: issue, Attempts: s.Attempt})
+		return
+	}
+	s.addFailure(&types.Failure{
 		Service:   s.Id,
 		Issue:     issue,
+		Attempts:  s.Attempt,
 		CreatedAt: time.Now(),
+	})
+}
+
+// addHit will save the hit and update the service's status. While a quorum of locations still agree the
+// service is offline it will stay offline, and an offline service will only be marked online once it has
+// RecoverAfter successful checks in a row.
+func (s *Service) addHit(hit *types.Hit) {
+	hit.Maintenance = s.UnderMaintenance() != nil
+	s.CreateHit(hit)
+	s.checkErrorBudget()
+	down := s.setLocation(hit.Location, true)
+	if down >= s.quorum() {
+		utils.Log(1, fmt.Sprintf("Service %v Successful from %v but offline at %v locations", s.Name, locationName(hit.Location), down))
+		return
 	}
-	utils.Log(2, fmt.Sprintf("Service %v Failing: %v", s.Name, issue))
+	if checkin := s.failingCheckin(); checkin != nil {
+		utils.Log(1, fmt.Sprintf("Service %v Successful from %v but checkin %v is failing", s.Name, locationName(hit.Location), checkin.Id))
+		return
+	}
+	firstCheck := s.FailureStreak == 0 && s.SuccessStreak == 0
+	s.FailureStreak = 0
+	s.SuccessStreak++
+	s.LastOnline = time.Now()
+	if !s.Online && !firstCheck && s.SuccessStreak < s.RecoverAfter {
+		utils.Log(1, fmt.Sprintf("Service %v Recovering (%v/%v): %0.2f ms", s.Name, s.SuccessStreak, s.RecoverAfter, hit.Latency*1000))
+		return
+	}
+	utils.Log(1, fmt.Sprintf("Service %v Successful: %0.2f ms", s.Name, hit.Latency*1000))
+	s.markOnline(!s.Online && !firstCheck)
+}
+
+// checkinRecovered will mark the service as online once none of its check-ins are failing, unless a quorum of
+// locations still agree the service is offline
+func (s *Service) checkinRecovered() {
+	if s.Online || s.failingCheckin() != nil {
+		return
+	}
+	if down := s.locationsDown(); down >= s.quorum() {
+		utils.Log(1, fmt.Sprintf("Service %v checkins recovered but offline at %v locations", s.Name, down))
+		return
+	}
+	s.LastOnline = time.Now()
+	s.markOnline(true)
+}
+
+// markOnline will set the service as online and send the notifications, recovered notifications are only
+// sent if the service was offline
+func (s *Service) markOnline(recovered bool) {
+	s.Online = true
+	notifier.OnSuccess(s.Service)
+	if recovered {
+		downtime := time.Now().Sub(s.DownSince)
+		utils.Log(1, fmt.Sprintf("Service %v Recovered after %v", s.Name, utils.DurationReadable(downtime)))
+		if s.DownNotified {
+			notifier.OnServiceRecovered(s.Service, downtime)
+		}
+	}
+	s.DownNotified = false
+}
+
+// addFailure will save the failure and update the service's status. A service will only be marked offline
+// when a quorum of locations are failing, and an online service will only be marked offline and send
+// notifications once it has FailAfter failed checks in a row. Notifications are not sent during a maintenance window
+// or while a service it depends on is offline.
+func (s *Service) addFailure(fail *types.Failure) {
+	maintenance := s.UnderMaintenance()
+	fail.Maintenance = maintenance != nil
+	dependency := s.dependencyDown()
+	fail.DependencyDown = dependency != nil
+	s.CreateFailure(fail)
+	s.checkErrorBudget()
+	down := s.setLocation(fail.Location, false)
+	if down < s.quorum() {
+		utils.Log(2, fmt.Sprintf("Service %v Failing from %v (%v/%v locations): %v", s.Name, locationName(fail.Location), down, s.quorum(), fail.Issue))
+		return
+	}
+	firstCheck := s.FailureStreak == 0 && s.SuccessStreak == 0
+	if firstCheck || (s.Online && s.FailureStreak == 0) {
+		s.DownSince = time.Now()
+	}
+	s.SuccessStreak = 0
+	s.FailureStreak++
+	if s.Online && !firstCheck && s.FailureStreak < s.FailAfter {
+		utils.Log(2, fmt.Sprintf("Service %v Failing (%v/%v): %v", s.Name, s.FailureStreak, s.FailAfter, fail.Issue))
+		return
+	}
+	s.markOffline(fail, maintenance, dependency)
+}
+
+// addCheckinFailure will save a failure from one of the service's check-ins and mark the service as offline. Check-ins
+// aren't checked from a location, so the failure doesn't need a quorum of locations or FailAfter failures in a row.
+func (s *Service) addCheckinFailure(fail *types.Failure) {
+	maintenance := s.UnderMaintenance()
+	fail.Maintenance = maintenance != nil
+	dependency := s.dependencyDown()
+	fail.DependencyDown = dependency != nil
 	s.CreateFailure(fail)
+	s.checkErrorBudget()
+	if s.Online || (s.FailureStreak == 0 && s.SuccessStreak == 0) {
+		s.DownSince = time.Now()
+	}
+	s.markOffline(fail, maintenance, dependency)
+}
+
+// markOffline will set the service as offline and send the notifications, unless the service is under maintenance
+// or a service it depends on is offline
+func (s *Service) markOffline(fail *types.Failure, maintenance *Maintenance, dependency *Service) {
+	s.Online = false
+	if maintenance != nil {
+		utils.Log(1, fmt.Sprintf("Service %v Failing during maintenance, notifications are paused: %v", s.Name, fail.Issue))
+		return
+	}
+	if dependency != nil {
+		utils.Log(1, fmt.Sprintf("Service %v Failing while its dependency %v is offline, notifications are paused: %v", s.Name, dependency.Name, fail.Issue))
+		return
+	}
+	utils.Log(2, fmt.Sprintf("Service %v Failing: %v", s.Name, fail.Issue))
 	notifier.OnFailure(s.Service, fail)
+	if !s.DownNotified {
+		s.DownNotified = true
+		notifier.OnServiceDown(s.Service, fail)
+	}
 }
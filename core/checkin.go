@@ -20,6 +20,7 @@ import (
 	"github.com/ararog/timeago"
 	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
+	"github.com/robfig/cron"
 	"time"
 )
 
@@ -35,12 +36,13 @@ func ReturnCheckin(s *types.Checkin) *Checkin {
 	return &Checkin{Checkin: s}
 }
 
-func FindCheckin(api string) *types.Checkin {
+// FindCheckin returns a check-in from in memory by its API key
+func FindCheckin(api string) *Checkin {
 	for _, ser := range CoreApp.Services {
 		service := ser.Select()
 		for _, c := range service.Checkins {
 			if c.Api == api {
-				return c
+				return ReturnCheckin(c)
 			}
 		}
 	}
@@ -55,13 +57,53 @@ func (s *Service) AllCheckins() []*types.Checkin {
 	return checkins
 }
 
+// ServiceCheckins returns the service's check-ins as a slice of *core.Checkin
+func (s *Service) ServiceCheckins() []*Checkin {
+	var checkins []*Checkin
+	for _, c := range s.Checkins {
+		checkins = append(checkins, ReturnCheckin(c))
+	}
+	return checkins
+}
+
+// startCheckins will start the expiration go routine for each of the service's check-ins
+func (s *Service) startCheckins() {
+	for _, c := range s.Checkins {
+		checkin := ReturnCheckin(c)
+		checkin.Start()
+		go checkin.Routine()
+	}
+}
+
+// closeCheckins will stop the expiration go routine for each of the service's check-ins
+func (s *Service) closeCheckins() {
+	for _, c := range s.Checkins {
+		c.Close()
+	}
+}
+
+// Create will insert the check-in into the database and start its expiration go routine
 func (u *Checkin) Create() (int64, error) {
+	if _, err := u.schedule(); err != nil {
+		utils.Log(2, fmt.Sprintf("Checkin has an invalid schedule %v: %v", u.Schedule, err))
+		return 0, err
+	}
+	if _, err := u.location(); err != nil {
+		utils.Log(2, fmt.Sprintf("Checkin has an invalid timezone %v: %v", u.Timezone, err))
+		return 0, err
+	}
 	u.CreatedAt = time.Now()
 	row := checkinDB().Create(u)
-	if row.Error == nil {
+	if row.Error != nil {
 		utils.Log(2, row.Error)
 		return 0, row.Error
 	}
+	service := SelectService(u.Service)
+	if service != nil {
+		service.Checkins = append([]*types.Checkin{u.Checkin}, service.Checkins...)
+	}
+	u.Start()
+	go u.Routine()
 	return u.Id, row.Error
 }
 
@@ -71,27 +113,192 @@ func SelectCheckinApi(api string) *Checkin {
 	return checkin
 }
 
+// Receivehit will record a successful ping for the check-in, the parent service will recover if the check-in was failing
+// and none of its other check-ins or locations are failing
 func (c *Checkin) Receivehit() {
 	c.Hits++
 	c.Last = time.Now()
+	err := checkinDB().Where("id = ?", c.Id).Updates(map[string]interface{}{"hits": c.Hits, "last": c.Last}).Error
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("Failed to update checkin %v: %v", c.Id, err))
+	}
+	c.createHit("success", "")
+	if !c.Failing {
+		return
+	}
+	c.Failing = false
+	service := SelectService(c.Service)
+	if service == nil {
+		return
+	}
+	utils.Log(1, fmt.Sprintf("Checkin %v for service %v received after it was expected", c.Id, service.Name))
+	service.checkinRecovered()
 }
 
-func (c *Checkin) RecheckCheckinFailure(guard chan struct{}) {
-	between := time.Now().Sub(c.Last).Seconds()
-	if between > float64(c.Interval) {
-		fmt.Println("rechecking every 15 seconds!")
-		c.CreateFailure()
-		time.Sleep(15 * time.Second)
-		guard <- struct{}{}
-		c.RecheckCheckinFailure(guard)
-	} else {
-		fmt.Println("i recovered!!")
+// ReceiveStart will record that the check-in's job has started, the next ping will record the job's runtime
+func (c *Checkin) ReceiveStart() {
+	c.Started = time.Now()
+	c.createHit("start", "")
+}
+
+// ReceiveFailure will record a failure reported by the check-in's job and mark the parent service as offline
+func (c *Checkin) ReceiveFailure(message string) {
+	c.Last = time.Now()
+	c.createHit("fail", message)
+	issue := fmt.Sprintf("Checkin %v reported a failure", c.Id)
+	if message != "" {
+		issue = fmt.Sprintf("%v: %v", issue, message)
+	}
+	c.CreateFailure(issue)
+}
+
+// createHit will save a ping into the check-in's history
+func (c *Checkin) createHit(status, message string) {
+	hit := &types.CheckinHit{
+		Checkin:   c.Id,
+		Status:    status,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	if status != "start" && !c.Started.IsZero() {
+		hit.Duration = hit.CreatedAt.Sub(c.Started).Seconds()
+		c.Started = time.Time{}
+	}
+	err := checkinHitsDB().Create(hit).Error
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("Failed to save checkin %v hit: %v", c.Id, err))
+	}
+}
+
+// History returns the most recent pings received for the check-in
+func (c *Checkin) History() []*types.CheckinHit {
+	var hits []*types.CheckinHit
+	checkinHitsDB().Where("checkin = ?", c.Id).Order("id desc").Limit(16).Find(&hits)
+	return hits
+}
+
+// schedule returns the check-in's parsed cron expression, or nil if the check-in uses an interval
+func (c *Checkin) schedule() (cron.Schedule, error) {
+	if c.Schedule == "" {
+		return nil, nil
 	}
-	<-guard
+	return cron.ParseStandard(c.Schedule)
 }
 
-func (f *Checkin) CreateFailure() {
+// location returns the timezone used for the check-in's cron expression, defaults to Statup's timezone
+func (c *Checkin) location() (*time.Location, error) {
+	if c.Timezone != "" {
+		return time.LoadLocation(c.Timezone)
+	}
+	if CoreApp == nil || CoreApp.Core == nil {
+		return time.UTC, nil
+	}
+	return time.FixedZone("", int(CoreApp.Timezone*3600)), nil
+}
 
+// next returns the time a ping is expected after t, by the cron expression or every interval
+func (c *Checkin) next(t time.Time) time.Time {
+	schedule, err := c.schedule()
+	if err != nil || schedule == nil {
+		return t.Add(c.period())
+	}
+	loc, err := c.location()
+	if err != nil {
+		loc = time.UTC
+	}
+	return schedule.Next(t.In(loc))
+}
+
+// Expected returns the time the next ping is required by, including the grace period
+func (c *Checkin) Expected() time.Time {
+	last := c.Last
+	if last.IsZero() {
+		last = c.CreatedAt
+	}
+	return c.next(last).Add(c.grace())
+}
+
+// grace returns the check-in's grace period as a duration
+func (c *Checkin) grace() time.Duration {
+	return time.Duration(c.GracePeriod) * time.Second
+}
+
+// period returns the check-in's interval as a duration, it will be at least 1 second
+func (c *Checkin) period() time.Duration {
+	if c.Interval < 1 {
+		return time.Second
+	}
+	return time.Duration(c.Interval) * time.Second
+}
+
+// ScheduleText returns a readable description of when the check-in expects a ping
+func (c *Checkin) ScheduleText() string {
+	if c.Schedule != "" {
+		zone := c.Timezone
+		if zone == "" {
+			zone = "Statup's timezone"
+		}
+		return fmt.Sprintf("on schedule %v (%v)", c.Schedule, zone)
+	}
+	return fmt.Sprintf("every %v", utils.DurationReadable(c.period()))
+}
+
+// failingCheckin returns the first of the service's check-ins that is failing, a successful service check will not
+// mark the service as online while a check-in is failing
+func (s *Service) failingCheckin() *types.Checkin {
+	for _, c := range s.Checkins {
+		if c.Failing {
+			return c
+		}
+	}
+	return nil
+}
+
+// Routine is the go routine that will create a failure each time an expected ping is overdue
+func (c *Checkin) Routine() {
+	for {
+		wait := c.Expected().Sub(time.Now())
+		if wait <= 0 {
+			wait = c.next(time.Now()).Add(c.grace()).Sub(time.Now())
+		}
+		select {
+		case <-c.Running:
+			return
+		case <-time.After(wait):
+			c.RecheckCheckinFailure()
+		}
+	}
+}
+
+// RecheckCheckinFailure will create a failure for the check-in if the last ping is overdue, returns true if failing
+func (c *Checkin) RecheckCheckinFailure() bool {
+	if time.Now().Before(c.Expected()) {
+		return false
+	}
+	c.CreateFailure(fmt.Sprintf("Checkin %v was expected %v, last ping was %v", c.Id, c.ScheduleText(), c.lastText()))
+	return true
+}
+
+// CreateFailure will mark the check-in as failing and create a failure on the parent service
+func (f *Checkin) CreateFailure(issue string) {
+	service := SelectService(f.Service)
+	if service == nil {
+		return
+	}
+	f.Failing = true
+	service.addCheckinFailure(&types.Failure{
+		Service:   service.Id,
+		Issue:     issue,
+		CreatedAt: time.Now(),
+	})
+}
+
+// lastText returns how long ago the last ping was received
+func (f *Checkin) lastText() string {
+	if f.Last.IsZero() {
+		return "never received"
+	}
+	return f.Ago()
 }
 
 func (f *Checkin) Ago() string {
@@ -62,6 +62,7 @@ func InitApp() {
 	checkServices()
 	CoreApp.Notifications = notifier.Load()
 	go DatabaseMaintence()
+	go RollupRoutine()
 }
 
 func insertNotifierDB() error {
@@ -78,6 +79,33 @@ func insertNotifierDB() error {
 // UpdateCore will update the CoreApp variable inside of the 'core' table in database
 func UpdateCore(c *Core) (*Core, error) {
 	db := coreDB().Update(&c)
+	if db.Error != nil {
+		return c, db.Error
+	}
+	// a retention can be cleared to keep records forever and login settings can be cleared, zero values
+	// are skipped when updating from the struct
+	db = coreDB().UpdateColumns(map[string]interface{}{
+		"hits_retention":       c.HitsRetention,
+		"failures_retention":   c.FailuresRetention,
+		"logs_retention":       c.LogsRetention,
+		"oidc_issuer":          c.OidcIssuer,
+		"oidc_client_id":       c.OidcClientId,
+		"oidc_client_secret":   c.OidcClientSecret,
+		"oidc_groups_claim":    c.OidcGroupsClaim,
+		"oidc_admin_groups":    c.OidcAdminGroups,
+		"oidc_operator_groups": c.OidcOperatorGroups,
+		"ldap_host":            c.LdapHost,
+		"ldap_start_tls":       c.LdapStartTLS,
+		"ldap_bind_dn":         c.LdapBindDn,
+		"ldap_bind_password":   c.LdapBindPassword,
+		"ldap_base_dn":         c.LdapBaseDn,
+		"ldap_filter":          c.LdapFilter,
+		"ldap_admin_group":     c.LdapAdminGroup,
+		"ldap_operator_group":  c.LdapOperatorGroup,
+		"disable_passwords":    c.DisablePasswords,
+		"require_two_factor":   c.RequireTwoFactor,
+		"trust_sso_two_factor": c.TrustSsoTwoFactor,
+	})
 	return c, db.Error
 }
 
@@ -61,11 +61,46 @@ func usersDB() *gorm.DB {
 	return DbSession.Model(&types.User{})
 }
 
-// hitsDB returns the 'hits' database column
+// checkinDB returns the 'checkins' database column
 func checkinDB() *gorm.DB {
 	return DbSession.Model(&types.Checkin{})
 }
 
+// maintenanceDB returns the 'maintenances' database column
+func maintenanceDB() *gorm.DB {
+	return DbSession.Model(&types.Maintenance{})
+}
+
+// incidentsDB returns the 'incidents' database column
+func incidentsDB() *gorm.DB {
+	return DbSession.Model(&types.Incident{})
+}
+
+// incidentUpdatesDB returns the 'incident_updates' database column
+func incidentUpdatesDB() *gorm.DB {
+	return DbSession.Model(&types.IncidentUpdate{})
+}
+
+// incidentServicesDB returns the 'incident_services' database column
+func incidentServicesDB() *gorm.DB {
+	return DbSession.Model(&types.IncidentService{})
+}
+
+// tokensDB returns the 'tokens' database column
+func tokensDB() *gorm.DB {
+	return DbSession.Model(&types.Token{})
+}
+
+// checkinHitsDB returns the 'checkin_hits' database column
+func checkinHitsDB() *gorm.DB {
+	return DbSession.Model(&types.CheckinHit{})
+}
+
+// rollupsDB returns the 'hourly_rollups' or 'daily_rollups' database table
+func rollupsDB(table string) *gorm.DB {
+	return DbSession.Table(table)
+}
+
 // HitsBetween returns the gorm database query for a collection of service hits between a time range
 func (s *Service) HitsBetween(t1, t2 time.Time, group string) *gorm.DB {
 	selector := Dbtimestamp(group)
@@ -128,13 +163,16 @@ func (u *Service) BeforeCreate() (err error) {
 // InsertCore create the single row for the Core settings in Statup
 func (db *DbConfig) InsertCore() (*Core, error) {
 	CoreApp = &Core{Core: &types.Core{
-		Name:        db.Project,
-		Description: db.Description,
-		Config:      "config.yml",
-		ApiKey:      utils.NewSHA1Hash(9),
-		ApiSecret:   utils.NewSHA1Hash(16),
-		Domain:      db.Domain,
-		MigrationId: time.Now().Unix(),
+		Name:              db.Project,
+		Description:       db.Description,
+		Config:            "config.yml",
+		ApiKey:            utils.NewSHA1Hash(9),
+		ApiSecret:         utils.NewSHA1Hash(16),
+		Domain:            db.Domain,
+		MigrationId:       time.Now().Unix(),
+		HitsRetention:     90,
+		FailuresRetention: 90,
+		LogsRetention:     30,
 	}}
 	CoreApp.DbConnection = db.DbConn
 	query := coreDB().Create(&CoreApp)
@@ -193,24 +231,18 @@ func (db *DbConfig) waitForDb() error {
 	return db.Connect(true, utils.Directory)
 }
 
-// DatabaseMaintence will automatically delete old records from 'failures' and 'hits'
-// this function is currently set to delete records 7+ days old every 60 minutes
+// DatabaseMaintence will delete the hits, failures and notifier logs that are older than their retention
+// settings when Statup starts, and again every 60 minutes
 func DatabaseMaintence() {
-	for range time.Tick(60 * time.Minute) {
-		utils.Log(1, "Checking for database records older than 3 months...")
-		since := time.Now().AddDate(0, -3, 0).UTC()
-		DeleteAllSince("failures", since)
-		DeleteAllSince("hits", since)
+	for {
+		CoreApp.Cleanup()
+		time.Sleep(60 * time.Minute)
 	}
 }
 
-// DeleteAllSince will delete a specific table's records based on a time.
-func DeleteAllSince(table string, date time.Time) {
-	sql := fmt.Sprintf("DELETE FROM %v WHERE created_at < '%v';", table, date.Format("2006-01-02"))
-	db := DbSession.Raw(sql)
-	if db.Error != nil {
-		utils.Log(2, db.Error)
-	}
+// DeleteAllSince will delete a table's records created before a time in batches, returning the amount deleted
+func DeleteAllSince(table string, date time.Time) (int64, error) {
+	return deleteBatches(table, "created_at < ?", date.UTC().Format(types.TIME))
 }
 
 // Update will save the config.yml file
@@ -277,12 +309,20 @@ func (c *DbConfig) CreateCore() *Core {
 func (db *DbConfig) DropDatabase() error {
 	utils.Log(1, "Dropping Database Tables...")
 	err := DbSession.DropTableIfExists("checkins")
+	err = DbSession.DropTableIfExists("hourly_rollups")
+	err = DbSession.DropTableIfExists("daily_rollups")
+	err = DbSession.DropTableIfExists("checkin_hits")
+	err = DbSession.DropTableIfExists("maintenances")
+	err = DbSession.DropTableIfExists("incidents")
+	err = DbSession.DropTableIfExists("incident_updates")
+	err = DbSession.DropTableIfExists("incident_services")
 	err = DbSession.DropTableIfExists("notifications")
 	err = DbSession.DropTableIfExists("core")
 	err = DbSession.DropTableIfExists("failures")
 	err = DbSession.DropTableIfExists("hits")
 	err = DbSession.DropTableIfExists("services")
 	err = DbSession.DropTableIfExists("users")
+	err = DbSession.DropTableIfExists("tokens")
 	return err.Error
 }
 
@@ -290,12 +330,20 @@ func (db *DbConfig) DropDatabase() error {
 func (db *DbConfig) CreateDatabase() error {
 	utils.Log(1, "Creating Database Tables...")
 	err := DbSession.CreateTable(&types.Checkin{})
+	err = DbSession.CreateTable(&types.CheckinHit{})
+	err = DbSession.Table("hourly_rollups").CreateTable(&types.Rollup{})
+	err = DbSession.Table("daily_rollups").CreateTable(&types.Rollup{})
+	err = DbSession.CreateTable(&types.Maintenance{})
+	err = DbSession.CreateTable(&types.Incident{})
+	err = DbSession.CreateTable(&types.IncidentUpdate{})
+	err = DbSession.CreateTable(&types.IncidentService{})
 	err = DbSession.CreateTable(&notifier.Notification{})
 	err = DbSession.Table("core").CreateTable(&types.Core{})
 	err = DbSession.CreateTable(&types.Failure{})
 	err = DbSession.CreateTable(&types.Hit{})
 	err = DbSession.CreateTable(&types.Service{})
 	err = DbSession.CreateTable(&types.User{})
+	err = DbSession.CreateTable(&types.Token{})
 	utils.Log(1, "Statup Database Created")
 	return err.Error
 }
@@ -315,7 +363,7 @@ func (db *DbConfig) MigrateDatabase() error {
 	if tx.Error != nil {
 		return tx.Error
 	}
-	tx = tx.AutoMigrate(&types.Service{}, &types.User{}, &types.Hit{}, &types.Failure{}, &types.Checkin{}, &notifier.Notification{}).Table("core").AutoMigrate(&types.Core{})
+	tx = tx.AutoMigrate(&types.Service{}, &types.User{}, &types.Token{}, &types.Hit{}, &types.Failure{}, &types.Checkin{}, &types.CheckinHit{}, &types.Maintenance{}, &types.Incident{}, &types.IncidentUpdate{}, &types.IncidentService{}, &notifier.Notification{}).Table("hourly_rollups").AutoMigrate(&types.Rollup{}).Table("daily_rollups").AutoMigrate(&types.Rollup{}).Table("core").AutoMigrate(&types.Core{})
 	if tx.Error != nil {
 		tx.Rollback()
 		utils.Log(3, fmt.Sprintf("Statup Database could not be migrated: %v", tx.Error))
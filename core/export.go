@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/hunterlong/statup/source"
+	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
 	"html/template"
 	"io/ioutil"
@@ -63,6 +64,9 @@ func ExportIndexHTML() string {
 		"CoreApp": func() *Core {
 			return CoreApp
 		},
+		"Services": func() []types.ServiceInterface {
+			return CoreApp.Services
+		},
 		"USE_CDN": func() bool {
 			return CoreApp.UseCdn
 		},
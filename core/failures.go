@@ -128,8 +128,39 @@ func (s *Service) TotalFailuresSince(ago time.Time) (uint64, error) {
 	return count, err.Error
 }
 
+// uptimeFailuresSince returns the amount of failures since a specific time/date, not including failures during a
+// maintenance window. Failures older than a week are counted from the hourly rollups, the failures before and after
+// the rollups are counted.
+func (s *Service) uptimeFailuresSince(ago time.Time) (uint64, error) {
+	sum := s.rollupsSince(ago)
+	if sum == nil {
+		return s.uptimeFailuresBetween(ago, time.Time{})
+	}
+	head, err := s.uptimeFailuresBetween(ago, sum.start)
+	if err != nil {
+		return 0, err
+	}
+	tail, err := s.uptimeFailuresBetween(sum.end, time.Time{})
+	return head + sum.failures + tail, err
+}
+
+// uptimeFailuresBetween returns the amount of failures from start until end that aren't during a maintenance window,
+// a zero end will count every failure since start
+func (s *Service) uptimeFailuresBetween(start, end time.Time) (uint64, error) {
+	var count uint64
+	rows := failuresDB().Where("service = ? AND created_at >= ? AND maintenance = ?", s.Id, start.UTC().Format("2006-01-02 15:04:05"), false)
+	if !end.IsZero() {
+		rows = rows.Where("created_at < ?", end.UTC().Format("2006-01-02 15:04:05"))
+	}
+	err := rows.Count(&count)
+	return count, err.Error
+}
+
 // ParseError returns a human readable error for a failure
 func (f *Failure) ParseError() string {
+	if f.DependencyDown {
+		return fmt.Sprintf("Dependency Down")
+	}
 	err := strings.Contains(f.Issue, "connection reset by peer")
 	if err {
 		return fmt.Sprintf("Connection Reset")
@@ -146,6 +177,10 @@ func (f *Failure) ParseError() string {
 	if err {
 		return fmt.Sprintf("Connection Timed Out")
 	}
+	err = strings.Contains(f.Issue, "i/o timeout")
+	if err {
+		return fmt.Sprintf("Connection Timed Out")
+	}
 	err = strings.Contains(f.Issue, "no such host")
 	if err {
 		return fmt.Sprintf("Domain is offline or not found")
@@ -0,0 +1,134 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+// ServiceGroup is a set of services with the same GroupName, shown as one section on the status page
+type ServiceGroup struct {
+	Name     string
+	Services []*Service
+}
+
+// ServiceGroups returns the services split into groups in the services order, services without a group
+// are returned in a group with an empty name
+func (c *Core) ServiceGroups() []*ServiceGroup {
+	var groups []*ServiceGroup
+	index := make(map[string]*ServiceGroup)
+	for _, s := range c.Services {
+		service := s.(*Service)
+		group, ok := index[service.GroupName]
+		if !ok {
+			group = &ServiceGroup{Name: service.GroupName}
+			index[service.GroupName] = group
+			groups = append(groups, group)
+		}
+		group.Services = append(group.Services, service)
+	}
+	return groups
+}
+
+// GroupNames returns the names of every service group
+func (c *Core) GroupNames() []string {
+	var names []string
+	for _, g := range c.ServiceGroups() {
+		if g.Name != "" {
+			names = append(names, g.Name)
+		}
+	}
+	return names
+}
+
+// OnlineCount returns the amount of online services in the group
+func (g *ServiceGroup) OnlineCount() int {
+	var count int
+	for _, s := range g.Services {
+		if s.Online {
+			count++
+		}
+	}
+	return count
+}
+
+// Online returns true if every service in the group is online
+func (g *ServiceGroup) Online() bool {
+	return g.OnlineCount() == len(g.Services)
+}
+
+// Status returns the aggregate status of the group, 'online', 'degraded' or 'offline'
+func (g *ServiceGroup) Status() string {
+	online := g.OnlineCount()
+	switch {
+	case online == len(g.Services):
+		return "online"
+	case online == 0:
+		return "offline"
+	default:
+		return "degraded"
+	}
+}
+
+// Parent returns the service this service depends on, or nil if it has no dependency
+func (s *Service) Parent() *Service {
+	if s.ParentId == 0 || s.ParentId == s.Id {
+		return nil
+	}
+	return SelectService(s.ParentId)
+}
+
+// Children returns the services that depend on this service
+func (s *Service) Children() []*Service {
+	var children []*Service
+	for _, c := range CoreApp.Services {
+		child := c.(*Service)
+		if child.ParentId == s.Id && child.Id != s.Id {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// dependencyDown returns the closest offline service in the service's dependency tree, or nil if they're all online
+func (s *Service) dependencyDown() *Service {
+	seen := map[int64]bool{s.Id: true}
+	for parent := s.Parent(); parent != nil; parent = parent.Parent() {
+		if seen[parent.Id] {
+			return nil
+		}
+		seen[parent.Id] = true
+		if !parent.Online {
+			return parent
+		}
+	}
+	return nil
+}
+
+// ValidParent returns true if the service can depend on the service id without creating a cycle
+func (s *Service) ValidParent(id int64) bool {
+	if id == 0 {
+		return true
+	}
+	parent := SelectService(id)
+	if parent == nil {
+		return false
+	}
+	seen := make(map[int64]bool)
+	for ; parent != nil && !seen[parent.Id]; parent = parent.Parent() {
+		if parent.Id == s.Id {
+			return false
+		}
+		seen[parent.Id] = true
+	}
+	return true
+}
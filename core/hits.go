@@ -75,6 +75,33 @@ func (s *Service) TotalHitsSince(ago time.Time) (uint64, error) {
 	return count, err.Error
 }
 
+// uptimeHitsSince returns the amount of hits since a specific time/date, not including hits during a maintenance window.
+// Hits older than a week are counted from the hourly rollups, the hits before and after the rollups are counted.
+func (s *Service) uptimeHitsSince(ago time.Time) (uint64, error) {
+	sum := s.rollupsSince(ago)
+	if sum == nil {
+		return s.uptimeHitsBetween(ago, time.Time{})
+	}
+	head, err := s.uptimeHitsBetween(ago, sum.start)
+	if err != nil {
+		return 0, err
+	}
+	tail, err := s.uptimeHitsBetween(sum.end, time.Time{})
+	return head + sum.hits + tail, err
+}
+
+// uptimeHitsBetween returns the amount of hits from start until end that aren't during a maintenance window,
+// a zero end will count every hit since start
+func (s *Service) uptimeHitsBetween(start, end time.Time) (uint64, error) {
+	var count uint64
+	rows := hitsDB().Where("service = ? AND created_at >= ? AND maintenance = ?", s.Id, start.UTC().Format("2006-01-02 15:04:05"), false)
+	if !end.IsZero() {
+		rows = rows.Where("created_at < ?", end.UTC().Format("2006-01-02 15:04:05"))
+	}
+	err := rows.Count(&count)
+	return count, err.Error
+}
+
 // Sum returns the added value Latency for all of the services successful hits.
 func (s *Service) Sum() (float64, error) {
 	var amount float64
@@ -0,0 +1,237 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ararog/timeago"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"strings"
+	"time"
+)
+
+type Incident struct {
+	*types.Incident
+}
+
+// ReturnIncident returns a *core.Incident
+func ReturnIncident(i *types.Incident) *Incident {
+	return &Incident{Incident: i}
+}
+
+// SelectIncident returns an incident with its services and updates based on its ID
+func SelectIncident(id int64) (*Incident, error) {
+	var incident Incident
+	err := incidentsDB().Where("id = ?", id).First(&incident)
+	if err.Error != nil {
+		return nil, err.Error
+	}
+	incident.load()
+	return &incident, nil
+}
+
+// AllIncidents returns every incident, newest first
+func AllIncidents() []*Incident {
+	var incidents []*Incident
+	err := incidentsDB().Order("id desc").Find(&incidents)
+	if err.Error != nil {
+		utils.Log(3, fmt.Sprintf("Issue getting incidents: %v", err.Error))
+		return nil
+	}
+	for _, i := range incidents {
+		i.load()
+	}
+	return incidents
+}
+
+// AllIncidents returns every incident for the dashboard
+func (c *Core) AllIncidents() []*Incident {
+	return AllIncidents()
+}
+
+// Incidents returns the incidents for the status page, open incidents and incidents resolved within the last 7 days
+func (c *Core) Incidents() []*Incident {
+	var incidents []*Incident
+	ago := time.Now().Add(-7 * 24 * time.Hour).UTC().Format("2006-01-02 15:04:05")
+	err := incidentsDB().Where("status != ? OR updated_at > ?", "resolved", ago).Order("id desc").Find(&incidents)
+	if err.Error != nil {
+		utils.Log(3, fmt.Sprintf("Issue getting incidents: %v", err.Error))
+		return nil
+	}
+	for _, i := range incidents {
+		i.load()
+	}
+	return incidents
+}
+
+// load will get the incident's affected services and timeline of updates
+func (i *Incident) load() {
+	var links []*types.IncidentService
+	incidentServicesDB().Where("incident = ?", i.Id).Find(&links)
+	i.Services = nil
+	for _, l := range links {
+		i.Services = append(i.Services, l.Service)
+	}
+	var updates []*types.IncidentUpdate
+	incidentUpdatesDB().Where("incident = ?", i.Id).Order("id desc").Find(&updates)
+	i.Updates = updates
+}
+
+// validIncidentStatus returns an error if the status is not one of types.IncidentStatuses
+func validIncidentStatus(status string) error {
+	for _, s := range types.IncidentStatuses {
+		if s == status {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("incident status must be one of %v", strings.Join(types.IncidentStatuses, ", ")))
+}
+
+// Create will insert a new incident and its affected services into the database
+func (i *Incident) Create() (int64, error) {
+	if i.Status == "" {
+		i.Status = "investigating"
+	}
+	if err := validIncidentStatus(i.Status); err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(i.Title) == "" {
+		return 0, errors.New("incident requires a title")
+	}
+	i.CreatedAt = time.Now()
+	db := incidentsDB().Create(i)
+	if db.Error != nil {
+		utils.Log(3, fmt.Sprintf("Failed to create incident %v: %v", i.Title, db.Error))
+		return 0, db.Error
+	}
+	return i.Id, i.saveServices()
+}
+
+// Update will save the incident's title, status and affected services
+func (i *Incident) Update() error {
+	if err := validIncidentStatus(i.Status); err != nil {
+		return err
+	}
+	db := incidentsDB().Where("id = ?", i.Id).Updates(map[string]interface{}{"title": i.Title, "status": i.Status, "updated_at": time.Now()})
+	if db.Error != nil {
+		utils.Log(3, fmt.Sprintf("Failed to update incident %v: %v", i.Title, db.Error))
+		return db.Error
+	}
+	return i.saveServices()
+}
+
+// saveServices will replace the incident's affected services in the database
+func (i *Incident) saveServices() error {
+	err := incidentServicesDB().Where("incident = ?", i.Id).Delete(&types.IncidentService{})
+	if err.Error != nil {
+		return err.Error
+	}
+	for _, s := range i.Services {
+		err = incidentServicesDB().Create(&types.IncidentService{Incident: i.Id, Service: s})
+		if err.Error != nil {
+			return err.Error
+		}
+	}
+	return nil
+}
+
+// Delete will remove the incident, its updates and its affected services from the database
+func (i *Incident) Delete() error {
+	incidentUpdatesDB().Where("incident = ?", i.Id).Delete(&types.IncidentUpdate{})
+	incidentServicesDB().Where("incident = ?", i.Id).Delete(&types.IncidentService{})
+	db := incidentsDB().Delete(i)
+	return db.Error
+}
+
+// AddUpdate will post an update to the incident's timeline and set the incident's status to the update's status
+func (i *Incident) AddUpdate(update *types.IncidentUpdate) (int64, error) {
+	if update.Status == "" {
+		update.Status = i.Status
+	}
+	if err := validIncidentStatus(update.Status); err != nil {
+		return 0, err
+	}
+	update.Incident = i.Id
+	update.CreatedAt = time.Now()
+	db := incidentUpdatesDB().Create(update)
+	if db.Error != nil {
+		utils.Log(3, fmt.Sprintf("Failed to create update for incident %v: %v", i.Title, db.Error))
+		return 0, db.Error
+	}
+	i.Status = update.Status
+	db = incidentsDB().Where("id = ?", i.Id).Updates(map[string]interface{}{"status": i.Status, "updated_at": time.Now()})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	i.Updates = append([]*types.IncidentUpdate{update}, i.Updates...)
+	return update.Id, nil
+}
+
+// Acknowledge will record the user that has taken responsibility for the incident
+func (i *Incident) Acknowledge(username string) error {
+	i.AcknowledgedBy = username
+	i.AcknowledgedAt = time.Now().UTC()
+	db := incidentsDB().Where("id = ?", i.Id).Updates(map[string]interface{}{"acknowledged_by": i.AcknowledgedBy, "acknowledged_at": i.AcknowledgedAt})
+	if db.Error != nil {
+		utils.Log(3, fmt.Sprintf("Failed to acknowledge incident %v: %v", i.Title, db.Error))
+	}
+	return db.Error
+}
+
+// Acknowledged returns true if a user has acknowledged the incident
+func (i *Incident) Acknowledged() bool {
+	return i.AcknowledgedBy != ""
+}
+
+// Resolved returns true if the incident has been resolved
+func (i *Incident) Resolved() bool {
+	return i.Status == "resolved"
+}
+
+// Affects returns true if the service is affected by the incident
+func (i *Incident) Affects(id int64) bool {
+	for _, s := range i.Services {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceNames returns a comma separated list of the incident's affected services
+func (i *Incident) ServiceNames() string {
+	var names []string
+	for _, id := range i.Services {
+		service := SelectService(id)
+		if service != nil {
+			names = append(names, service.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Ago returns a human readable timestamp for when the incident was created
+func (i *Incident) Ago() string {
+	got, _ := timeago.TimeAgoWithTime(time.Now(), i.CreatedAt)
+	return got
+}
+
+// IncidentStatuses returns the statuses an incident can have
+func (c *Core) IncidentStatuses() []string {
+	return types.IncidentStatuses
+}
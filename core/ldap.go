@@ -0,0 +1,196 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"gopkg.in/ldap.v2"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ldapDefaultFilter finds users by their uid, Active Directory servers can use '(sAMAccountName=%s)'
+const ldapDefaultFilter = "(uid=%s)"
+
+var (
+	ldapTimeout = 10 * time.Second
+	// ldapRootCAs verifies the LDAP server's certificate, nil uses the system's root certificates
+	ldapRootCAs *x509.CertPool
+)
+
+// LDAPEnabled returns true if users can login with a LDAP or Active Directory server
+func (c *Core) LDAPEnabled() bool {
+	return c.LdapHost != "" && c.LdapBaseDn != ""
+}
+
+// AuthLDAP will return the User and true if the username and password bind to the LDAP server. The user is
+// found by searching the base DN with the filter, where %s is replaced with the username. Users are created
+// the first time they login and found by their DN after that, a login with the username of a local or single
+// sign-on user is refused. The role is set from their memberOf groups when admin or operator groups are
+// configured, otherwise new users are viewers.
+func (c *Core) AuthLDAP(username, password string) (*User, bool) {
+	if !c.LDAPEnabled() || username == "" || password == "" {
+		return nil, false
+	}
+	entry, err := c.ldapLogin(username, password)
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("LDAP login for %v failed: %v", username, err))
+		return nil, false
+	}
+	syncRole := c.LdapAdminGroup != "" || c.LdapOperatorGroup != ""
+	user, err := provisionUser(types.SourceLDAP, entry.DN, username, ldapFirst(entry, "mail"), c.ldapRole(ldapAttribute(entry, "memberOf")), syncRole)
+	if err == ErrUserExists {
+		utils.Log(2, fmt.Sprintf("LDAP user %v has the username of an existing user that is not from LDAP", username))
+		return nil, false
+	}
+	if err != nil {
+		utils.Log(3, fmt.Sprintf("Issue creating LDAP user %v: %v", username, err))
+		return nil, false
+	}
+	return user, true
+}
+
+// ldapLogin returns the user's LDAP entry after binding as the user with their password
+func (c *Core) ldapLogin(username, password string) (*ldap.Entry, error) {
+	conn, err := dialLDAP(c.LdapHost, c.LdapStartTLS)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if c.LdapBindDn != "" {
+		if c.LdapBindPassword == "" {
+			return nil, errors.New("bind password is required, an empty password would be an unauthenticated bind")
+		}
+		if err := conn.Bind(c.LdapBindDn, c.LdapBindPassword); err != nil {
+			return nil, fmt.Errorf("could not bind as %v: %v", c.LdapBindDn, err)
+		}
+	}
+	filter := c.ldapUserFilter(username)
+	// a login only needs to know if there is more than one match
+	search := ldap.NewSearchRequest(c.LdapBaseDn, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout/time.Second),
+		false, filter, []string{"mail", "memberOf"}, nil)
+	result, err := conn.Search(search)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("%v users matched %v", len(result.Entries), filter)
+	}
+	if err := conn.Bind(result.Entries[0].DN, password); err != nil {
+		return nil, err
+	}
+	return result.Entries[0], nil
+}
+
+// ldapUserFilter returns the search filter for the username, the username is escaped so it can't change the filter
+func (c *Core) ldapUserFilter(username string) string {
+	filter := strings.TrimSpace(c.LdapFilter)
+	if filter == "" {
+		filter = ldapDefaultFilter
+	}
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	return strings.Replace(filter, "%s", ldap.EscapeFilter(username), -1)
+}
+
+// dialLDAP connects to a LDAP server address like 'ldap://host:389', 'ldaps://host:636' or 'host:389'.
+// StartTLS is used to secure ldap:// connections when startTLS is true.
+func dialLDAP(address string, startTLS bool) (*ldap.Conn, error) {
+	if !strings.Contains(address, "://") {
+		address = "ldap://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "389"
+		if u.Scheme == "ldaps" {
+			port = "636"
+		}
+	}
+	host := net.JoinHostPort(u.Hostname(), port)
+	dialer := &net.Dialer{Timeout: ldapTimeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.Dial("tcp", host)
+	case "ldaps":
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, ldapTLSConfig(u.Hostname()))
+	default:
+		return nil, fmt.Errorf("LDAP server scheme must be ldap:// or ldaps://, not %v", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := ldap.NewConn(conn, u.Scheme == "ldaps")
+	l.Start()
+	l.SetTimeout(ldapTimeout)
+	if startTLS && u.Scheme == "ldap" {
+		if err := l.StartTLS(ldapTLSConfig(u.Hostname())); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func ldapTLSConfig(host string) *tls.Config {
+	return &tls.Config{ServerName: host, RootCAs: ldapRootCAs}
+}
+
+// ldapAttribute returns the values of an entry's attribute, attribute names are case insensitive
+func ldapAttribute(entry *ldap.Entry, name string) []string {
+	for _, attr := range entry.Attributes {
+		if strings.EqualFold(attr.Name, name) {
+			return attr.Values
+		}
+	}
+	return nil
+}
+
+// ldapFirst returns the first value of an entry's attribute
+func ldapFirst(entry *ldap.Entry, name string) string {
+	values := ldapAttribute(entry, name)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// ldapRole returns the role for a user's memberOf group DNs, users in neither group are viewers
+func (c *Core) ldapRole(groups []string) string {
+	for _, g := range groups {
+		if c.LdapAdminGroup != "" && strings.EqualFold(strings.TrimSpace(g), strings.TrimSpace(c.LdapAdminGroup)) {
+			return types.RoleAdmin
+		}
+	}
+	for _, g := range groups {
+		if c.LdapOperatorGroup != "" && strings.EqualFold(strings.TrimSpace(g), strings.TrimSpace(c.LdapOperatorGroup)) {
+			return types.RoleOperator
+		}
+	}
+	return types.RoleViewer
+}
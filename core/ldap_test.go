@@ -0,0 +1,178 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/asn1-ber.v1"
+	"gopkg.in/ldap.v2"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// ldapDirectory are the DNs and passwords of the mock LDAP server
+var ldapDirectory = map[string]string{
+	"cn=statup,dc=example,dc=com":               "service",
+	"uid=ldapuser,ou=people,dc=example,dc=com":  "secret",
+	"uid=ldaplocal,ou=people,dc=example,dc=com": "secret",
+}
+
+// testCertificate returns a self signed certificate for 127.0.0.1 and a pool that trusts it
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// mockLDAPServer starts a LDAP server with a service account that can search for the people in ldapDirectory,
+// and returns its address
+func mockLDAPServer(t *testing.T, cert tls.Certificate) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveLDAP(conn, cert)
+		}
+	}()
+	return listener.Addr().String(), func() { listener.Close() }
+}
+
+func serveLDAP(conn net.Conn, cert tls.Certificate) {
+	defer conn.Close()
+	var bound string
+	reply := func(id int64, op *ber.Packet) {
+		msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+		msg.AppendChild(op)
+		conn.Write(msg.Bytes())
+	}
+	result := func(tag ber.Tag, code int64) *ber.Packet {
+		p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+		p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+		p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+		p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+		return p
+	}
+	for {
+		msg, err := ber.ReadPacket(conn)
+		if err != nil || len(msg.Children) < 2 {
+			return
+		}
+		id, _ := msg.Children[0].Value.(int64)
+		op := msg.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationExtendedRequest:
+			reply(id, result(ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess))
+			conn = tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+		case ldap.ApplicationBindRequest:
+			dn, _ := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			if password == "" || ldapDirectory[dn] != password {
+				reply(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials))
+				continue
+			}
+			bound = dn
+			reply(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess))
+		case ldap.ApplicationSearchRequest:
+			if bound != "cn=statup,dc=example,dc=com" {
+				reply(id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights))
+				continue
+			}
+			uid := filterUID(op.Children[6])
+			dn := "uid=" + uid + ",ou=people,dc=example,dc=com"
+			if _, ok := ldapDirectory[dn]; ok {
+				reply(id, ldapSearchEntry(dn, map[string][]string{
+					"mail":     {uid + "@example.com"},
+					"memberOf": {"cn=Staff,ou=groups,dc=example,dc=com", "cn=Admins,ou=groups,dc=example,dc=com"},
+				}))
+			}
+			reply(id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		default:
+			return
+		}
+	}
+}
+
+// ldapSearchEntry returns a search result entry with the DN and attributes
+func ldapSearchEntry(dn string, attributes map[string][]string) *ber.Packet {
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, ""))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	entry.AppendChild(attrs)
+	return entry
+}
+
+// filterUID returns the value of the first uid equality match in a search filter
+func filterUID(p *ber.Packet) string {
+	if p.ClassType == ber.ClassContext && p.Tag == ldap.FilterEqualityMatch && len(p.Children) == 2 && p.Children[0].Value == "uid" {
+		uid, _ := p.Children[1].Value.(string)
+		return uid
+	}
+	for _, c := range p.Children {
+		if uid := filterUID(c); uid != "" {
+			return uid
+		}
+	}
+	return ""
+}
+
+func TestLDAPFilter(t *testing.T) {
+	defer func() { CoreApp.LdapFilter = "" }()
+	assert.Equal(t, `(uid=a\2a\28b\29\5c)`, CoreApp.ldapUserFilter(`a*(b)\`))
+	CoreApp.LdapFilter = "sAMAccountName=%s"
+	assert.Equal(t, `(sAMAccountName=jdoe)`, CoreApp.ldapUserFilter("jdoe"))
+	CoreApp.LdapFilter = "(&(objectClass=person)(uid=%s))"
+	assert.Equal(t, `(&(objectClass=person)(uid=j\2a))`, CoreApp.ldapUserFilter("j*"))
+}
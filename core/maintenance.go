@@ -0,0 +1,154 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"time"
+)
+
+type Maintenance struct {
+	*types.Maintenance
+}
+
+// ReturnMaintenance returns a *core.Maintenance
+func ReturnMaintenance(m *types.Maintenance) *Maintenance {
+	return &Maintenance{Maintenance: m}
+}
+
+// SelectMaintenance returns a maintenance window based on its ID
+func SelectMaintenance(id int64) (*Maintenance, error) {
+	var maintenance Maintenance
+	err := maintenanceDB().Where("id = ?", id).First(&maintenance)
+	return &maintenance, err.Error
+}
+
+// AllMaintenance returns every maintenance window, ordered by its start
+func AllMaintenance() []*Maintenance {
+	var maintenance []*Maintenance
+	err := maintenanceDB().Order("start_time desc").Find(&maintenance)
+	if err.Error != nil {
+		utils.Log(3, fmt.Sprintf("Issue getting maintenance windows: %v", err.Error))
+	}
+	return maintenance
+}
+
+// AllMaintenance returns every maintenance window for the settings page
+func (c *Core) AllMaintenance() []*Maintenance {
+	return AllMaintenance()
+}
+
+// Create will insert a new maintenance window into the database
+func (m *Maintenance) Create() (int64, error) {
+	if !m.End.After(m.Start) {
+		return 0, errors.New("maintenance window must end after it starts")
+	}
+	period := m.period()
+	if m.Recurring != "" && period == 0 {
+		return 0, errors.New(fmt.Sprintf("maintenance window has an unknown recurring value %v", m.Recurring))
+	}
+	if period != 0 && m.End.Sub(m.Start) >= period {
+		return 0, errors.New("maintenance window must be shorter than its recurring period")
+	}
+	m.CreatedAt = time.Now()
+	db := maintenanceDB().Create(m)
+	if db.Error != nil {
+		utils.Log(3, fmt.Sprintf("Failed to create maintenance window: %v", db.Error))
+		return 0, db.Error
+	}
+	return m.Id, db.Error
+}
+
+// Delete will remove a maintenance window from the database
+func (m *Maintenance) Delete() error {
+	db := maintenanceDB().Delete(m)
+	return db.Error
+}
+
+// period returns how often the window repeats, or 0 for a one-off window
+func (m *Maintenance) period() time.Duration {
+	switch m.Recurring {
+	case "daily":
+		return 24 * time.Hour
+	case "weekly":
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// ActiveAt returns true if the maintenance window includes the time t
+func (m *Maintenance) ActiveAt(t time.Time) bool {
+	if t.Before(m.Start) {
+		return false
+	}
+	period := m.period()
+	if period == 0 {
+		return t.Before(m.End)
+	}
+	offset := t.Sub(m.Start) % period
+	return offset < m.End.Sub(m.Start)
+}
+
+// ServiceName returns the name of the maintenance window's service
+func (m *Maintenance) ServiceName() string {
+	if m.Service == 0 {
+		return "All Services"
+	}
+	service := SelectService(m.Service)
+	if service == nil {
+		return fmt.Sprintf("Service #%v", m.Service)
+	}
+	return service.Name
+}
+
+// WindowText returns a readable description of when the maintenance window happens
+func (m *Maintenance) WindowText() string {
+	zone := CoreApp.Timezone
+	start := utils.Timezoner(m.Start, zone)
+	end := utils.Timezoner(m.End, zone)
+	switch m.Recurring {
+	case "daily":
+		return fmt.Sprintf("Every day from %v to %v, starting %v", start.Format("3:04PM"), end.Format("3:04PM"), start.Format("Jan _2 2006"))
+	case "weekly":
+		return fmt.Sprintf("Every week from %v to %v, starting %v", start.Format("Monday 3:04PM"), end.Format("Monday 3:04PM"), start.Format("Jan _2 2006"))
+	default:
+		return fmt.Sprintf("%v to %v", start.Format("Monday 3:04PM, Jan _2 2006"), end.Format("Monday 3:04PM, Jan _2 2006"))
+	}
+}
+
+// UnderMaintenance returns the active maintenance window for a service, or nil if there isn't one
+func (s *Service) UnderMaintenance() *Maintenance {
+	return s.maintenanceAt(time.Now())
+}
+
+// maintenanceAt returns the maintenance window for the service, or for all services, that includes the time t
+func (s *Service) maintenanceAt(t time.Time) *Maintenance {
+	if DbSession == nil {
+		return nil
+	}
+	var windows []*Maintenance
+	maintenanceDB().Where("service = ? OR service = 0", s.Id).Find(&windows)
+	for _, m := range windows {
+		if m.ActiveAt(t) {
+			return m
+		}
+	}
+	return nil
+}
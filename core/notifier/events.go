@@ -15,7 +15,10 @@
 
 package notifier
 
-import "github.com/hunterlong/statup/types"
+import (
+	"github.com/hunterlong/statup/types"
+	"time"
+)
 
 // OnSave will trigger a notifier when it has been saved - Notifier interface
 func OnSave(method string) {
@@ -47,6 +50,33 @@ func OnSuccess(s *types.Service) {
 	}
 }
 
+// OnServiceDown is triggered once when a service goes offline - TransitionEvents interface
+func OnServiceDown(s *types.Service, f *types.Failure) {
+	for _, comm := range AllCommunications {
+		if isType(comm, new(TransitionEvents)) && isEnabled(comm) && inLimits(comm) {
+			comm.(TransitionEvents).OnServiceDown(s, f)
+		}
+	}
+}
+
+// OnServiceRecovered is triggered once when an offline service is back online, with the outage duration - TransitionEvents interface
+func OnServiceRecovered(s *types.Service, downtime time.Duration) {
+	for _, comm := range AllCommunications {
+		if isType(comm, new(TransitionEvents)) && isEnabled(comm) && inLimits(comm) {
+			comm.(TransitionEvents).OnServiceRecovered(s, downtime)
+		}
+	}
+}
+
+// OnBudgetBurn is triggered once when a service's error budget burn rate is too high - BudgetEvents interface
+func OnBudgetBurn(s *types.Service, slo *types.SLO) {
+	for _, comm := range AllCommunications {
+		if isType(comm, new(BudgetEvents)) && isEnabled(comm) && inLimits(comm) {
+			comm.(BudgetEvents).OnBudgetBurn(s, slo)
+		}
+	}
+}
+
 // OnNewService is triggered when a new service is created - ServiceEvents interface
 func OnNewService(s *types.Service) {
 	for _, comm := range AllCommunications {
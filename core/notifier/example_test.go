@@ -115,6 +115,24 @@ func (n *ExampleNotifier) OnFailure(s *types.Service, f *types.Failure) {
 	n.AddQueue(msg)
 }
 
+// OPTIONAL
+func (n *ExampleNotifier) OnServiceDown(s *types.Service, f *types.Failure) {
+	msg := fmt.Sprintf("received a service down trigger for service: %v\n", s.Name)
+	n.AddQueue(msg)
+}
+
+// OPTIONAL
+func (n *ExampleNotifier) OnServiceRecovered(s *types.Service, downtime time.Duration) {
+	msg := fmt.Sprintf("received a service recovered trigger for service: %v after %v\n", s.Name, downtime)
+	n.AddQueue(msg)
+}
+
+// OPTIONAL
+func (n *ExampleNotifier) OnBudgetBurn(s *types.Service, slo *types.SLO) {
+	msg := fmt.Sprintf("received a budget burn trigger for service: %v at %vx\n", s.Name, slo.BurnRate)
+	n.AddQueue(msg)
+}
+
 // OPTIONAL Test function before user saves
 func (n *ExampleNotifier) OnTest() error {
 	fmt.Printf("received a test trigger with form data: %v\n", n.Host)
@@ -15,7 +15,10 @@
 
 package notifier
 
-import "github.com/hunterlong/statup/types"
+import (
+	"github.com/hunterlong/statup/types"
+	"time"
+)
 
 // Notifier interface is required to create a new Notifier
 type Notifier interface {
@@ -30,6 +33,17 @@ type BasicEvents interface {
 	OnFailure(*types.Service, *types.Failure) // OnFailure is triggered when a service is failing
 }
 
+// TransitionEvents are only triggered when a service changes from online to offline, or offline to online
+type TransitionEvents interface {
+	OnServiceDown(*types.Service, *types.Failure)     // OnServiceDown is triggered when a service goes offline
+	OnServiceRecovered(*types.Service, time.Duration) // OnServiceRecovered is triggered when a service is back online with the outage duration
+}
+
+// BudgetEvents are triggered when a service with an SLO is using its error budget too fast
+type BudgetEvents interface {
+	OnBudgetBurn(*types.Service, *types.SLO) // OnBudgetBurn is triggered once when the burn rate reaches the service's alert threshold
+}
+
 // Tester interface will include a function to Test users settings before saving
 type Tester interface {
 	OnTest() error
@@ -174,6 +174,24 @@ func (n *Notification) Logs() []*NotificationLog {
 	return reverseLogs(n.logs)
 }
 
+// PruneLogs will remove the logs recorded before a time from every notifier, returning the amount of logs removed
+func PruneLogs(before time.Time) int {
+	var removed int
+	for _, comm := range AllCommunications {
+		n := asNotification(comm)
+		var logs []*NotificationLog
+		for _, l := range n.logs {
+			if l.Timestamp.Before(before) {
+				removed++
+				continue
+			}
+			logs = append(logs, l)
+		}
+		n.logs = logs
+	}
+	return removed
+}
+
 // reverseLogs will reverse the notifier's logs to be time desc
 func reverseLogs(input []*NotificationLog) []*NotificationLog {
 	if len(input) == 0 {
@@ -187,54 +187,72 @@ func TestOnFailure(t *testing.T) {
 	assert.Equal(t, 8, len(example.Queue))
 }
 
+func TestOnServiceDown(t *testing.T) {
+	OnServiceDown(service, failure)
+	assert.Equal(t, 9, len(example.Queue))
+}
+
+func TestOnServiceRecovered(t *testing.T) {
+	OnServiceRecovered(service, 5*time.Minute)
+	assert.Equal(t, 10, len(example.Queue))
+}
+
 func TestOnNewService(t *testing.T) {
 	OnNewService(service)
-	assert.Equal(t, 9, len(example.Queue))
+	assert.Equal(t, 11, len(example.Queue))
 }
 
 func TestOnUpdatedService(t *testing.T) {
 	OnUpdatedService(service)
-	assert.Equal(t, 10, len(example.Queue))
+	assert.Equal(t, 12, len(example.Queue))
 }
 
 func TestOnDeletedService(t *testing.T) {
 	OnDeletedService(service)
-	assert.Equal(t, 11, len(example.Queue))
+	assert.Equal(t, 13, len(example.Queue))
 }
 
 func TestOnNewUser(t *testing.T) {
 	OnNewUser(user)
-	assert.Equal(t, 12, len(example.Queue))
+	assert.Equal(t, 14, len(example.Queue))
 }
 
 func TestOnUpdatedUser(t *testing.T) {
 	OnUpdatedUser(user)
-	assert.Equal(t, 13, len(example.Queue))
+	assert.Equal(t, 15, len(example.Queue))
 }
 
 func TestOnDeletedUser(t *testing.T) {
 	OnDeletedUser(user)
-	assert.Equal(t, 14, len(example.Queue))
+	assert.Equal(t, 16, len(example.Queue))
 }
 
 func TestOnUpdatedCore(t *testing.T) {
 	OnUpdatedCore(core)
-	assert.Equal(t, 15, len(example.Queue))
+	assert.Equal(t, 17, len(example.Queue))
 }
 
 func TestOnUpdatedNotifier(t *testing.T) {
 	OnUpdatedNotifier(example.Select())
-	assert.Equal(t, 16, len(example.Queue))
+	assert.Equal(t, 18, len(example.Queue))
 }
 
 func TestRunAllQueueAndStop(t *testing.T) {
 	assert.True(t, example.IsRunning())
-	assert.Equal(t, 16, len(example.Queue))
+	assert.Equal(t, 18, len(example.Queue))
 	go Queue(example)
-	assert.Equal(t, 16, len(example.Queue))
+	assert.Equal(t, 18, len(example.Queue))
 	time.Sleep(10 * time.Second)
-	assert.Equal(t, 6, len(example.Queue))
+	assert.Equal(t, 8, len(example.Queue))
 	example.close()
 	assert.False(t, example.IsRunning())
-	assert.Equal(t, 6, len(example.Queue))
+	assert.Equal(t, 8, len(example.Queue))
+}
+
+func TestPruneLogs(t *testing.T) {
+	logs := len(example.Logs())
+	assert.NotZero(t, logs)
+	assert.Equal(t, 0, PruneLogs(time.Now().Add(-time.Hour)))
+	assert.Equal(t, logs, PruneLogs(time.Now()))
+	assert.Len(t, example.Logs(), 0)
 }
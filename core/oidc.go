@@ -0,0 +1,423 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hunterlong/statup/types"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// oidcClockSkew is how far the ID token's expiry can be off from the local clock
+	oidcClockSkew = time.Minute
+	// oidcKeyRefresh is how long the provider's keys are used before a token with an unknown key ID can request them again
+	oidcKeyRefresh = time.Minute
+)
+
+var (
+	oidcClient    = &http.Client{Timeout: 15 * time.Second}
+	oidcProviders = make(map[string]*oidcProvider)
+	oidcLock      sync.Mutex
+)
+
+// oidcProvider contains the endpoints from an OpenID Connect provider's discovery document
+type oidcProvider struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JwksURL  string `json:"jwks_uri"`
+
+	keysLock    sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+// oidcKey is a RSA public key from the provider's JSON Web Key Set
+type oidcKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// OIDCClaims are the claims from a verified ID token that are used to login a User. Email is only set
+// when the provider verified it.
+type OIDCClaims struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// ID returns the identity of the user at the provider, the subject is only unique for its issuer
+func (o *OIDCClaims) ID() string {
+	return o.Issuer + "#" + o.Subject
+}
+
+// OIDCEnabled returns true if single sign-on with an OpenID Connect provider is configured
+func (c *Core) OIDCEnabled() bool {
+	return c.OidcIssuer != "" && c.OidcClientId != ""
+}
+
+// PasswordLoginDisabled returns true if users can't login with their Statup password, only with single sign-on
+// or LDAP. Password login is never disabled when neither is configured, so admins can't lock themselves out.
+func (c *Core) PasswordLoginDisabled() bool {
+	return c.DisablePasswords && (c.OIDCEnabled() || c.LDAPEnabled())
+}
+
+// OIDCAuthURL returns the provider's URL to send a user to for login, with the state and nonce that have
+// to be checked when the provider sends the user back to the redirect URL
+func (c *Core) OIDCAuthURL(redirect string) (string, string, string, error) {
+	p, err := discoverOIDC(c.OidcIssuer)
+	if err != nil {
+		return "", "", "", err
+	}
+	state, err := randomHex(16)
+	if err != nil {
+		return "", "", "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", "", "", err
+	}
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.OidcClientId)
+	query.Set("redirect_uri", redirect)
+	query.Set("scope", "openid profile email")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	sep := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.AuthURL + sep + query.Encode(), state, nonce, nil
+}
+
+// OIDCExchange trades the code from the provider's redirect for an ID token, and returns its claims after
+// checking the token's signature, issuer, audience, expiry and nonce
+func (c *Core) OIDCExchange(code, redirect, nonce string) (*OIDCClaims, error) {
+	p, err := discoverOIDC(c.OidcIssuer)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirect)
+	req, err := http.NewRequest("POST", p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.OidcClientId), url.QueryEscape(c.OidcClientSecret))
+	resp, err := oidcClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var token struct {
+		IdToken     string `json:"id_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	json.NewDecoder(resp.Body).Decode(&token)
+	if resp.StatusCode != http.StatusOK || token.IdToken == "" {
+		return nil, fmt.Errorf("provider did not return an ID token: %v %v %v", resp.Status, token.Error, token.Description)
+	}
+	claims, err := verifyIDToken(p, token.IdToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkClaims(claims, p.Issuer, c.OidcClientId, nonce); err != nil {
+		return nil, err
+	}
+	return c.oidcClaims(claims), nil
+}
+
+// OIDCUser returns the User for the claims, users are found by their issuer and subject and created the first
+// time they login. The user's role is set from their groups on every login when admin or operator groups are
+// configured, otherwise new users are viewers.
+func (c *Core) OIDCUser(claims *OIDCClaims) (*User, error) {
+	if claims.Subject == "" || claims.Username == "" {
+		return nil, errors.New("ID token does not have a subject")
+	}
+	syncRole := c.OidcAdminGroups != "" || c.OidcOperatorGroups != ""
+	return provisionUser(types.SourceOIDC, claims.ID(), claims.Username, claims.Email, c.oidcRole(claims.Groups), syncRole)
+}
+
+// LinkOIDC will link the local user to the single sign-on account of the claims
+func (c *Core) LinkOIDC(u *User, claims *OIDCClaims) error {
+	if claims.Subject == "" {
+		return errors.New("ID token does not have a subject")
+	}
+	return u.LinkSubject(claims.ID())
+}
+
+// oidcRole returns the role for a user's groups, users in none of the configured groups are viewers
+func (c *Core) oidcRole(groups []string) string {
+	if inGroups(c.OidcAdminGroups, groups) {
+		return types.RoleAdmin
+	}
+	if inGroups(c.OidcOperatorGroups, groups) {
+		return types.RoleOperator
+	}
+	return types.RoleViewer
+}
+
+// inGroups returns true if one of the groups is in a comma separated list of groups
+func inGroups(list string, groups []string) bool {
+	for _, g := range strings.Split(list, ",") {
+		g = strings.TrimSpace(g)
+		if g != "" && inScopes(groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// oidcClaims returns the OIDCClaims from an ID token's claims. The email is only used when the email_verified
+// claim is true, and the username is the preferred_username, email or subject claim, the first one that is set.
+func (c *Core) oidcClaims(claims map[string]interface{}) *OIDCClaims {
+	iss, _ := claims["iss"].(string)
+	sub, _ := claims["sub"].(string)
+	var email string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		if verified {
+			email, _ = claims["email"].(string)
+		}
+	case string:
+		if verified == "true" {
+			email, _ = claims["email"].(string)
+		}
+	}
+	username, _ := claims["preferred_username"].(string)
+	if username == "" {
+		username = email
+	}
+	if username == "" {
+		username = sub
+	}
+	groupsClaim := c.OidcGroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	var groups []string
+	switch v := claims[groupsClaim].(type) {
+	case []interface{}:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	case string:
+		for _, g := range strings.Split(v, ",") {
+			groups = append(groups, strings.TrimSpace(g))
+		}
+	}
+	return &OIDCClaims{
+		Issuer:   iss,
+		Subject:  sub,
+		Username: username,
+		Email:    email,
+		Groups:   groups,
+	}
+}
+
+// discoverOIDC returns the provider's endpoints from its discovery document, which is only requested once per issuer
+func discoverOIDC(issuer string) (*oidcProvider, error) {
+	issuer = strings.TrimRight(issuer, "/")
+	oidcLock.Lock()
+	defer oidcLock.Unlock()
+	if p, ok := oidcProviders[issuer]; ok {
+		return p, nil
+	}
+	var p *oidcProvider
+	if err := oidcGet(issuer+"/.well-known/openid-configuration", &p); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("provider issuer %v does not match %v", p.Issuer, issuer)
+	}
+	if p.AuthURL == "" || p.TokenURL == "" || p.JwksURL == "" {
+		return nil, fmt.Errorf("provider %v is missing authorization, token or jwks endpoints", issuer)
+	}
+	oidcProviders[issuer] = p
+	return p, nil
+}
+
+// oidcGet will decode the JSON response of a GET request to the provider
+func oidcGet(address string, v interface{}) error {
+	resp, err := oidcClient.Get(address)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned %v", address, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// verifyIDToken checks the RS256 signature of an ID token with the provider's keys and returns its claims
+func verifyIDToken(p *oidcProvider, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("ID token is not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("ID token algorithm %v is not supported, use RS256", header.Alg)
+	}
+	key, err := oidcPublicKey(p, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+		return nil, errors.New("ID token signature is invalid")
+	}
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// oidcPublicKey returns the provider's RSA key with the key ID, or its only RSA key if the token has no key ID.
+// The keys are cached by key ID and only requested again when the provider signs with a key that isn't cached,
+// for key rotation, at most once every oidcKeyRefresh.
+func oidcPublicKey(p *oidcProvider, kid string) (*rsa.PublicKey, error) {
+	p.keysLock.Lock()
+	defer p.keysLock.Unlock()
+	if key := p.cachedKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) > oidcKeyRefresh {
+		keys, err := fetchOIDCKeys(p.JwksURL)
+		if err != nil {
+			return nil, err
+		}
+		p.keys = keys
+		p.keysFetched = time.Now()
+		if key := p.cachedKey(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("provider does not have a RSA key for key ID '%v'", kid)
+}
+
+// cachedKey returns the cached key with the key ID, a token without a key ID can only use the provider's only key
+func (p *oidcProvider) cachedKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+// fetchOIDCKeys returns the RSA keys of a JSON Web Key Set by their key ID
+func fetchOIDCKeys(address string) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []oidcKey `json:"keys"`
+	}
+	if err := oidcGet(address, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// checkClaims returns an error if the ID token wasn't issued by the provider for this client and nonce, or has expired
+func checkClaims(claims map[string]interface{}, issuer, clientId, nonce string) error {
+	if iss, _ := claims["iss"].(string); iss != issuer {
+		return fmt.Errorf("ID token issuer %v does not match %v", iss, issuer)
+	}
+	var audience []string
+	switch v := claims["aud"].(type) {
+	case string:
+		audience = append(audience, v)
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+	}
+	if !inScopes(audience, clientId) {
+		return errors.New("ID token was not issued for this client")
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Add(-oidcClockSkew).After(time.Unix(int64(exp), 0)) {
+		return errors.New("ID token has expired")
+	}
+	if n, _ := claims["nonce"].(string); nonce == "" || n != nonce {
+		return errors.New("ID token nonce does not match")
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64 JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,120 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"time"
+)
+
+type Outage struct {
+	*types.Outage
+}
+
+// Outages returns the service's last outages, newest first, computed from its hits and failures
+func (s *Service) Outages(limit int) []*Outage {
+	var outages []*Outage
+	cursor := time.Now().Add(time.Minute)
+	for len(outages) < limit {
+		var last types.Failure
+		err := failuresDB().Where("service = ? AND created_at < ?", s.Id, cursor.UTC().Format(types.TIME)).Order("created_at desc").First(&last)
+		if err.Error != nil {
+			break
+		}
+		outage := s.outage(&last)
+		outages = append(outages, outage)
+		cursor = outage.Start
+	}
+	return outages
+}
+
+// CurrentOutage returns the service's ongoing outage, or nil if its last check was successful
+func (s *Service) CurrentOutage() *Outage {
+	outages := s.Outages(1)
+	if len(outages) == 0 || !outages[0].Ongoing {
+		return nil
+	}
+	return outages[0]
+}
+
+// outage returns the outage that includes the failure. It starts with the first failure after the
+// previous hit and ends with the next hit, or is ongoing if there hasn't been a hit since.
+func (s *Service) outage(failure *types.Failure) *Outage {
+	first := failure
+	starts := failuresDB().Where("service = ?", s.Id)
+	var before types.Hit
+	err := hitsDB().Where("service = ? AND created_at < ?", s.Id, failure.CreatedAt.UTC().Format(types.TIME)).Order("created_at desc").First(&before)
+	if err.Error == nil {
+		starts = starts.Where("created_at > ?", before.CreatedAt.UTC().Format(types.TIME))
+	}
+	var start types.Failure
+	if starts.Order("created_at asc").First(&start).Error == nil {
+		first = &start
+	}
+	outage := &Outage{&types.Outage{
+		Start:  first.CreatedAt,
+		Reason: (&Failure{first}).ParseError(),
+		Issue:  first.Issue,
+	}}
+	failures := failuresDB().Where("service = ? AND created_at >= ?", s.Id, outage.Start.UTC().Format(types.TIME))
+	var after types.Hit
+	err = hitsDB().Where("service = ? AND created_at > ?", s.Id, failure.CreatedAt.UTC().Format(types.TIME)).Order("created_at asc").First(&after)
+	if err.Error == nil {
+		outage.End = after.CreatedAt
+		failures = failures.Where("created_at < ?", outage.End.UTC().Format(types.TIME))
+	} else {
+		outage.Ongoing = true
+	}
+	failures.Count(&outage.Failures)
+	outage.Duration = int64(outage.Downtime().Seconds())
+	return outage
+}
+
+// Downtime returns the duration of the outage, until now if it's ongoing
+func (o *Outage) Downtime() time.Duration {
+	if o.Ongoing {
+		return time.Now().Sub(o.Start)
+	}
+	return o.End.Sub(o.Start)
+}
+
+// DowntimeText returns a human readable duration of the outage
+func (o *Outage) DowntimeText() string {
+	return utils.DurationReadable(o.Downtime())
+}
+
+// StartText returns a human readable timestamp for when the outage started
+func (o *Outage) StartText() string {
+	return utils.Timezoner(o.Start, CoreApp.Timezone).Format("Jan _2 2006 3:04:05PM")
+}
+
+// EndText returns a human readable timestamp for when the outage ended
+func (o *Outage) EndText() string {
+	if o.Ongoing {
+		return "Ongoing"
+	}
+	return utils.Timezoner(o.End, CoreApp.Timezone).Format("Jan _2 2006 3:04:05PM")
+}
+
+// FailuresText returns a readable amount of failures during the outage
+func (o *Outage) FailuresText() string {
+	if o.Failures == 1 {
+		return "1 failure"
+	}
+	return fmt.Sprintf("%v failures", o.Failures)
+}
@@ -0,0 +1,248 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Probe is set when Statup is running as a remote probe. Service checks will be sent to the main Statup
+// instance instead of being saved into the database.
+var Probe *Prober
+
+var locationsLock sync.Mutex
+
+// staleLocationIntervals is the amount of check intervals a location can miss before its status is dropped
+const staleLocationIntervals = 3
+
+// Prober pulls the services from a main Statup instance's API, checks them, and pushes the results back
+// with its location
+type Prober struct {
+	Endpoint  string
+	ApiSecret string
+	Location  string
+	Refresh   time.Duration
+	services  []*Service
+	client    *http.Client
+}
+
+// NewProber returns a Prober for the main Statup instance at endpoint
+func NewProber(endpoint, secret, location string) *Prober {
+	return &Prober{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		ApiSecret: secret,
+		Location:  location,
+		Refresh:   time.Minute,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// request will send a HTTP request to the main Statup instance's API
+func (p *Prober) request(method, path string, data interface{}) (*http.Response, error) {
+	var body bytes.Buffer
+	if data != nil {
+		if err := json.NewEncoder(&body).Encode(data); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, p.Endpoint+path, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.ApiSecret)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.New(fmt.Sprintf("%v %v returned status code %v", method, path, res.StatusCode))
+	}
+	return res, nil
+}
+
+// Services returns the services from the main Statup instance
+func (p *Prober) Services() ([]*Service, error) {
+	res, err := p.request("GET", "/api/services", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var services []*types.Service
+	err = json.NewDecoder(res.Body).Decode(&services)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Service
+	for _, s := range services {
+		service := ReturnService(s)
+		service.normalize()
+		out = append(out, service)
+	}
+	return out, nil
+}
+
+// Report will send the result of a service check to the main Statup instance
+func (p *Prober) Report(s *Service, result *types.ProbeResult) {
+	result.Location = p.Location
+	s.Online = result.Online
+	res, err := p.request("POST", fmt.Sprintf("/api/services/%v/probe", s.Id), result)
+	if err != nil {
+		utils.Log(3, fmt.Sprintf("Probe could not send the result for service %v: %v", s.Name, err))
+		return
+	}
+	res.Body.Close()
+	utils.Log(1, fmt.Sprintf("Probe sent service %v result from %v, online: %v", s.Name, p.Location, result.Online))
+}
+
+// Run will check the services from the main Statup instance, the list of services is pulled again on every Refresh
+func (p *Prober) Run() {
+	for {
+		if err := p.refresh(); err != nil {
+			utils.Log(3, fmt.Sprintf("Probe could not get services from %v: %v", p.Endpoint, err))
+		}
+		time.Sleep(p.Refresh)
+	}
+}
+
+// refresh will pull the services from the main Statup instance. Services that were added are started, services
+// that were removed are stopped, and services that were updated are restarted with their new settings.
+func (p *Prober) refresh() error {
+	services, err := p.Services()
+	if err != nil {
+		return err
+	}
+	running := make(map[int64]*Service)
+	for _, s := range p.services {
+		running[s.Id] = s
+	}
+	var checking []*Service
+	for _, s := range services {
+		current, ok := running[s.Id]
+		delete(running, s.Id)
+		if ok && current.UpdatedAt.Equal(s.UpdatedAt) {
+			checking = append(checking, current)
+			continue
+		}
+		if ok {
+			current.Close()
+		}
+		s.Start()
+		go s.CheckQueue(true)
+		checking = append(checking, s)
+	}
+	for _, s := range running {
+		s.Close()
+	}
+	if len(checking) != len(p.services) {
+		utils.Log(1, fmt.Sprintf("Probe %v is checking %v Services from %v", p.Location, len(checking), p.Endpoint))
+	}
+	p.services = checking
+	return nil
+}
+
+// RecordProbe will save the result of a service check sent from a remote probe
+func (s *Service) RecordProbe(result *types.ProbeResult) {
+	if result.Online {
+		s.addHit(&types.Hit{
+			Service:      s.Id,
+			Latency:      result.Latency,
+			DnsLookup:    result.DnsLookup,
+			ConnectTime:  result.ConnectTime,
+			TLSHandshake: result.TLSHandshake,
+			FirstByte:    result.FirstByte,
+			Location:     result.Location,
+			CreatedAt:    time.Now(),
+		})
+		return
+	}
+	s.addFailure(&types.Failure{
+		Service:   s.Id,
+		Issue:     result.Issue,
+		Attempts:  result.Attempts,
+		Location:  result.Location,
+		CreatedAt: time.Now(),
+	})
+}
+
+// setLocation will save the status for a location and return the amount of locations the service is offline at
+func (s *Service) setLocation(location string, online bool) int {
+	locationsLock.Lock()
+	defer locationsLock.Unlock()
+	if s.Locations == nil {
+		s.Locations = make(map[string]*types.LocationStatus)
+	}
+	s.Locations[location] = &types.LocationStatus{Online: online, LastSeen: time.Now()}
+	return s.countLocationsDown()
+}
+
+// locationsDown returns the amount of locations the service is offline at
+func (s *Service) locationsDown() int {
+	locationsLock.Lock()
+	defer locationsLock.Unlock()
+	return s.countLocationsDown()
+}
+
+// countLocationsDown returns the amount of locations the service is offline at, locationsLock must be held. Locations
+// that haven't reported for a few intervals are dropped, so a probe that was shut down won't keep the service offline.
+func (s *Service) countLocationsDown() int {
+	var down int
+	for location, status := range s.Locations {
+		if time.Since(status.LastSeen) > s.locationExpiry() {
+			delete(s.Locations, location)
+			continue
+		}
+		if !status.Online {
+			down++
+		}
+	}
+	return down
+}
+
+// locationExpiry returns how long the status from a location is counted towards the quorum, at least a minute
+func (s *Service) locationExpiry() time.Duration {
+	expiry := staleLocationIntervals * time.Duration(s.Interval+s.Timeout) * time.Second
+	if expiry < time.Minute {
+		return time.Minute
+	}
+	return expiry
+}
+
+// quorum returns the amount of locations that need to fail before the service is offline
+func (s *Service) quorum() int {
+	if s.Quorum < 1 {
+		return 1
+	}
+	return s.Quorum
+}
+
+// locationName returns a readable name for a location, the main Statup instance has an empty location
+func locationName(location string) string {
+	if location == "" {
+		return "local"
+	}
+	return location
+}
@@ -0,0 +1,144 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"github.com/hunterlong/statup/core/notifier"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"time"
+)
+
+// retentionBatch is the most records removed by a single DELETE query
+const retentionBatch = 1000
+
+// RetentionResult is the amount of records removed by a database cleanup
+type RetentionResult struct {
+	Time     time.Time
+	Hits     int64
+	Failures int64
+	Logs     int
+}
+
+// lastCleanup is the result of the latest database cleanup, nil until the first cleanup has finished
+var lastCleanup *RetentionResult
+
+// Ago returns a human readable time of the cleanup
+func (r *RetentionResult) Ago() string {
+	return utils.Timestamp(r.Time).Ago()
+}
+
+// LastCleanup returns the result of the latest database cleanup, or nil if one hasn't finished yet
+func (c *Core) LastCleanup() *RetentionResult {
+	return lastCleanup
+}
+
+// retentionDays returns the service's retention if it has been set, otherwise the global retention. A retention of
+// -1 keeps records forever and a retention of 0 hasn't been set, for services and globally.
+func retentionDays(service, global int) int {
+	if service != 0 {
+		return service
+	}
+	return global
+}
+
+// retentionCutoff returns the time records older than the amount of days are removed at, it returns false if the
+// records should be kept forever because the retention is -1, or it hasn't been set
+func retentionCutoff(days int) (time.Time, bool) {
+	if days <= 0 {
+		return time.Time{}, false
+	}
+	return time.Now().UTC().AddDate(0, 0, -days), true
+}
+
+// Cleanup will remove the hits, failures and notifier logs older than the global and service retention settings.
+// A service's hits are saved into rollups before they're removed so its long term charts and uptime are kept.
+func (c *Core) Cleanup() *RetentionResult {
+	result := &RetentionResult{Time: time.Now()}
+	var ids []int64
+	for _, s := range c.Services {
+		service := s.(*Service)
+		ids = append(ids, service.Id)
+		service.Rollup()
+		if cutoff, ok := retentionCutoff(retentionDays(service.HitsRetention, c.HitsRetention)); ok {
+			result.Hits += service.deleteBefore("hits", cutoff)
+		}
+		if cutoff, ok := retentionCutoff(retentionDays(service.FailuresRetention, c.FailuresRetention)); ok {
+			result.Failures += service.deleteBefore("failures", cutoff)
+		}
+	}
+	if cutoff, ok := retentionCutoff(c.HitsRetention); ok {
+		result.Hits += deleteOrphans("hits", cutoff, ids)
+	}
+	if cutoff, ok := retentionCutoff(c.FailuresRetention); ok {
+		result.Failures += deleteOrphans("failures", cutoff, ids)
+	}
+	if cutoff, ok := retentionCutoff(c.LogsRetention); ok {
+		result.Logs = notifier.PruneLogs(cutoff)
+	}
+	utils.Log(1, fmt.Sprintf("Database cleanup removed %v hits, %v failures and %v notifier logs", result.Hits, result.Failures, result.Logs))
+	lastCleanup = result
+	return result
+}
+
+// deleteBefore will remove the service's records in a table created before a time, returning the amount removed
+func (s *Service) deleteBefore(table string, cutoff time.Time) int64 {
+	deleted, err := deleteBatches(table, "service = ? AND created_at < ?", s.Id, cutoff.Format(types.TIME))
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("Issue removing old %v for service %v: %v", table, s.Name, err))
+	}
+	return deleted
+}
+
+// deleteOrphans will remove the records in a table created before a time for services that have been deleted
+func deleteOrphans(table string, cutoff time.Time, services []int64) int64 {
+	var deleted int64
+	var err error
+	if len(services) == 0 {
+		deleted, err = DeleteAllSince(table, cutoff)
+	} else {
+		deleted, err = deleteBatches(table, "service NOT IN (?) AND created_at < ?", services, cutoff.Format(types.TIME))
+	}
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("Issue removing old %v for deleted services: %v", table, err))
+	}
+	return deleted
+}
+
+// deleteBatches will remove the records in a table matching a query, at most retentionBatch records at a time
+// so large tables aren't locked by a single query. It returns the amount of records removed.
+func deleteBatches(table string, query string, args ...interface{}) (int64, error) {
+	var deleted int64
+	for {
+		var ids []int64
+		err := DbSession.Table(table).Where(query, args...).Limit(retentionBatch).Pluck("id", &ids)
+		if err.Error != nil {
+			return deleted, err.Error
+		}
+		if len(ids) == 0 {
+			return deleted, nil
+		}
+		err = DbSession.Exec(fmt.Sprintf("DELETE FROM %v WHERE id IN (?)", table), ids)
+		if err.Error != nil {
+			return deleted, err.Error
+		}
+		deleted += err.RowsAffected
+		if len(ids) < retentionBatch {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rollupTable is a table of rollups for a graph group, each rollup covers one period
+type rollupTable struct {
+	Group  string
+	Name   string
+	Period time.Duration
+}
+
+// rollupTables are the hourly and daily rollup tables
+var rollupTables = []*rollupTable{
+	{Group: "hour", Name: "hourly_rollups", Period: time.Hour},
+	{Group: "day", Name: "daily_rollups", Period: 24 * time.Hour},
+}
+
+// rollupAge is how old the start of a graph or uptime range must be to read from the rollups instead of every hit and failure
+const rollupAge = 7 * 24 * time.Hour
+
+// rollupLock keeps the rollup routine and the database cleanup from saving the same rollups at once
+var rollupLock sync.Mutex
+
+// findRollupTable returns the rollup table for a graph group, or nil if the group doesn't have rollups
+func findRollupTable(group string) *rollupTable {
+	for _, t := range rollupTables {
+		if t.Group == group {
+			return t
+		}
+	}
+	return nil
+}
+
+// RollupRoutine will save the rollups for each service's completed hours and days every 15 minutes
+func RollupRoutine() {
+	for {
+		for _, s := range CoreApp.Services {
+			s.(*Service).Rollup()
+		}
+		time.Sleep(15 * time.Minute)
+	}
+}
+
+// Rollup will save the hourly and daily rollups for every completed hour and day since the service's last rollups
+func (s *Service) Rollup() {
+	rollupLock.Lock()
+	defer rollupLock.Unlock()
+	for _, t := range rollupTables {
+		err := s.rollup(t, time.Now().UTC().Truncate(t.Period))
+		if err != nil {
+			utils.Log(2, fmt.Sprintf("Issue saving %v for service %v: %v", t.Name, s.Name, err))
+		}
+	}
+}
+
+// rollup will save a rollup for each period from the service's last rollup until a time, the hits and
+// failures are loaded one day at a time
+func (s *Service) rollup(t *rollupTable, until time.Time) error {
+	from, ok := s.nextRollup(t)
+	if !ok {
+		return nil
+	}
+	for day := from; day.Before(until); day = day.Add(24 * time.Hour) {
+		end := day.Add(24 * time.Hour)
+		if end.After(until) {
+			end = until
+		}
+		var hits []*types.Hit
+		err := hitsDB().Where("service = ? AND created_at >= ? AND created_at < ?", s.Id, day.Format(types.TIME), end.Format(types.TIME)).Find(&hits)
+		if err.Error != nil {
+			return err.Error
+		}
+		var failures []*types.Failure
+		err = failuresDB().Where("service = ? AND created_at >= ? AND created_at < ?", s.Id, day.Format(types.TIME), end.Format(types.TIME)).Find(&failures)
+		if err.Error != nil {
+			return err.Error
+		}
+		for _, r := range summarize(s.Id, t.Period, hits, failures) {
+			err = rollupsDB(t.Name).Create(r)
+			if err.Error != nil {
+				return err.Error
+			}
+		}
+	}
+	return nil
+}
+
+// nextRollup returns the start of the service's next rollup, after its last rollup or at the period of its first check.
+// It returns false if the service hasn't been checked yet.
+func (s *Service) nextRollup(t *rollupTable) (time.Time, bool) {
+	var last types.Rollup
+	err := rollupsDB(t.Name).Where("service = ?", s.Id).Order("start_time desc").First(&last)
+	if err.Error == nil {
+		return last.Start.UTC().Add(t.Period), true
+	}
+	var first []time.Time
+	var hit types.Hit
+	if hitsDB().Where("service = ?", s.Id).Order("created_at asc").First(&hit).Error == nil {
+		first = append(first, hit.CreatedAt)
+	}
+	var failure types.Failure
+	if failuresDB().Where("service = ?", s.Id).Order("created_at asc").First(&failure).Error == nil {
+		first = append(first, failure.CreatedAt)
+	}
+	if len(first) == 0 {
+		return time.Time{}, false
+	}
+	sort.Slice(first, func(i, j int) bool { return first[i].Before(first[j]) })
+	return first[0].UTC().Truncate(t.Period), true
+}
+
+// summarize returns a rollup for each period that has hits or failures, in order
+func summarize(service int64, period time.Duration, hits []*types.Hit, failures []*types.Failure) []*types.Rollup {
+	index := make(map[time.Time]*types.Rollup)
+	latencies := make(map[time.Time][]float64)
+	var rollups []*types.Rollup
+	get := func(created time.Time) *types.Rollup {
+		start := created.UTC().Truncate(period)
+		r, ok := index[start]
+		if !ok {
+			r = &types.Rollup{Service: service, Start: start}
+			index[start] = r
+			rollups = append(rollups, r)
+		}
+		return r
+	}
+	for _, h := range hits {
+		r := get(h.CreatedAt)
+		r.Hits++
+		if !h.Maintenance {
+			r.UptimeHits++
+		}
+		latencies[r.Start] = append(latencies[r.Start], h.Latency)
+	}
+	for _, f := range failures {
+		r := get(f.CreatedAt)
+		r.Failures++
+		if !f.Maintenance {
+			r.UptimeFailures++
+		}
+	}
+	for _, r := range rollups {
+		values := latencies[r.Start]
+		if len(values) == 0 {
+			continue
+		}
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		r.AvgLatency = sum / float64(len(values))
+		r.P50Latency = percentile(values, 50)
+		r.P95Latency = percentile(values, 95)
+		r.P99Latency = percentile(values, 99)
+		// percentile has sorted the latencies
+		r.MinLatency = values[0]
+		r.MaxLatency = values[len(values)-1]
+	}
+	sort.Slice(rollups, func(i, j int) bool { return rollups[i].Start.Before(rollups[j].Start) })
+	return rollups
+}
+
+// rollupSum is the total of a service's hourly rollups, with the time the first rollup starts and the last rollup ends
+type rollupSum struct {
+	hits     uint64
+	failures uint64
+	start    time.Time
+	end      time.Time
+}
+
+// rollupsSince returns the total hits and failures outside of maintenance windows in the service's hourly rollups that
+// start after a time. It returns nil if the time is too recent to use rollups, or there aren't any rollups since then.
+// The hits and failures before the first rollup starts and after the last rollup ends are not included.
+func (s *Service) rollupsSince(ago time.Time) *rollupSum {
+	if time.Now().Sub(ago) < rollupAge {
+		return nil
+	}
+	t := findRollupTable("hour")
+	rows := rollupsDB(t.Name).Where("service = ? AND start_time >= ?", s.Id, ago.UTC().Format(types.TIME))
+	var first, last types.Rollup
+	if rows.Order("start_time desc").First(&last).Error != nil {
+		return nil
+	}
+	if rows.Order("start_time asc").First(&first).Error != nil {
+		return nil
+	}
+	var hits, failures sql.NullInt64
+	err := rows.Select("SUM(uptime_hits), SUM(uptime_failures)").Row().Scan(&hits, &failures)
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("Issue getting rollups for service %v: %v", s.Name, err))
+		return nil
+	}
+	return &rollupSum{
+		hits:     uint64(hits.Int64),
+		failures: uint64(failures.Int64),
+		start:    first.Start.UTC(),
+		end:      last.Start.UTC().Add(t.Period),
+	}
+}
+
+// graphDataRollup returns the service's graph data from the rollup table, with the hits after the last rollup added
+// to the end. It returns nil if there aren't any rollups between the two times.
+func graphDataRollup(s *Service, t *rollupTable, start, end time.Time, stat string) *DateScanObj {
+	var rollups []*types.Rollup
+	err := rollupsDB(t.Name).Where("service = ? AND start_time BETWEEN ? AND ?", s.Id, start.UTC().Format(types.TIME), end.UTC().Format(types.TIME)).Order("start_time asc").Find(&rollups)
+	if err.Error != nil || len(rollups) == 0 {
+		return nil
+	}
+	var d []DateScan
+	for _, r := range rollups {
+		if r.Hits == 0 {
+			continue
+		}
+		d = append(d, DateScan{
+			CreatedAt: utils.Timezoner(r.Start.UTC(), CoreApp.Timezone).Format(types.TIME),
+			Value:     int64(rollupLatency(r, stat) * 1000),
+		})
+	}
+	covered := rollups[len(rollups)-1].Start.UTC().Add(t.Period)
+	var hits []*types.Hit
+	hitsDB().Where("service = ? AND created_at >= ? AND created_at <= ?", s.Id, covered.Format(types.TIME), end.UTC().Format(types.TIME)).Order("created_at asc").Find(&hits)
+	d = append(d, hitsScan(hits, t.Period, graphPercentiles[stat])...)
+	return &DateScanObj{d}
+}
+
+// rollupLatency returns the rollup's latency for a graph stat, 'p50', 'p95', 'p99' or the average latency
+func rollupLatency(r *types.Rollup, stat string) float64 {
+	switch stat {
+	case "p50":
+		return r.P50Latency
+	case "p95":
+		return r.P95Latency
+	case "p99":
+		return r.P99Latency
+	default:
+		return r.AvgLatency
+	}
+}
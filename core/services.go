@@ -24,6 +24,7 @@ import (
 	"github.com/hunterlong/statup/utils"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -59,6 +60,7 @@ func (c *Core) SelectAllServices() ([]*Service, error) {
 	}
 	CoreApp.Services = nil
 	for _, service := range services {
+		service.normalize()
 		service.Start()
 		service.AllCheckins()
 		service.AllFailures()
@@ -98,14 +100,15 @@ func (s *Service) Online24() float32 {
 	return s.OnlineSince(ago)
 }
 
-// OnlineSince accepts a time since parameter to return the percent of a service's uptime.
+// OnlineSince accepts a time since parameter to return the percent of a service's uptime. Hits and failures
+// during a maintenance window are not included.
 func (s *Service) OnlineSince(ago time.Time) float32 {
-	failed, _ := s.TotalFailuresSince(ago)
+	failed, _ := s.uptimeFailuresSince(ago)
 	if failed == 0 {
 		s.Online24Hours = 100.00
 		return s.Online24Hours
 	}
-	total, _ := s.TotalHitsSince(ago)
+	total, _ := s.uptimeHitsSince(ago)
 	if total == 0 {
 		s.Online24Hours = 0
 		return s.Online24Hours
@@ -166,7 +169,22 @@ func (s *Service) DowntimeText() string {
 	return fmt.Sprintf("%v has been offline for %v", s.Name, utils.DurationReadable(s.Downtime()))
 }
 
-func Dbtimestamp(group string) string {
+// TimingText returns a readable breakdown of the time spent in each phase of the service's last HTTP check
+func (s *Service) TimingText() string {
+	if s.FirstByte == 0 {
+		return ""
+	}
+	return fmt.Sprintf("DNS %0.0fms, Connect %0.0fms, TLS %0.0fms, First Byte %0.0fms, Total %0.0fms", s.DnsLookup*1000, s.ConnectTime*1000, s.TLSHandshake*1000, s.FirstByte*1000, s.Latency*1000)
+}
+
+// CertExpiryText returns a readable sentence of the service's TLS certificate expiry, issuer and SANs
+func (s *Service) CertExpiryText() string {
+	expiry := utils.Timezoner(s.CertExpiry, CoreApp.Timezone).Format("Monday, January 02 2006")
+	return fmt.Sprintf("TLS Certificate expires on %v, issued by %v for %v", expiry, s.CertIssuer, strings.Join(s.CertDNSNames, ", "))
+}
+
+// groupSeconds returns the amount of seconds in a graph group of 'second', 'minute', 'hour' or 'day'
+func groupSeconds(group string) int {
 	seconds := 60
 	if group == "second" {
 		seconds = 60
@@ -175,6 +193,11 @@ func Dbtimestamp(group string) string {
 	} else if group == "day" {
 		seconds = 86400
 	}
+	return seconds
+}
+
+func Dbtimestamp(group string) string {
+	seconds := groupSeconds(group)
 	switch CoreApp.DbConnection {
 	case "mysql":
 		return fmt.Sprintf("CONCAT(date_format(created_at, '%%Y-%%m-%%d %%H:00:00')) AS timeframe, AVG(latency) AS value")
@@ -187,23 +210,32 @@ func Dbtimestamp(group string) string {
 	}
 }
 
-// Downtime returns the amount of time of a offline service
+// Downtime returns the amount of time of the service's ongoing outage, or 0 if its last check was successful
 func (s *Service) Downtime() time.Duration {
-	hits, _ := s.Hits()
-	fails := s.LimitedFailures()
-	if len(fails) == 0 {
+	outage := s.CurrentOutage()
+	if outage == nil {
 		return time.Duration(0)
 	}
-	if len(hits) == 0 {
-		return time.Now().UTC().Sub(fails[len(fails)-1].CreatedAt.UTC())
-	}
-	since := fails[0].CreatedAt.UTC().Sub(hits[0].CreatedAt.UTC())
-	return since
+	return outage.Downtime()
 }
 
-func GraphDataRaw(service types.ServiceInterface, start, end time.Time, group string) *DateScanObj {
+// graphPercentiles are the stats GraphDataRaw can return as a latency percentile instead of the average latency
+var graphPercentiles = map[string]float64{"p50": 50, "p95": 95, "p99": 99}
+
+// GraphDataRaw returns the service's latency between two times, grouped by 'minute', 'hour' or 'day'. The stat can be
+// 'p50', 'p95' or 'p99' to return a latency percentile for each group, otherwise the average latency is returned.
+func GraphDataRaw(service types.ServiceInterface, start, end time.Time, group string, stat string) *DateScanObj {
+	s := service.(*Service)
+	if t := findRollupTable(group); t != nil && time.Now().Sub(start) > rollupAge {
+		if data := graphDataRollup(s, t, start, end, stat); data != nil {
+			return data
+		}
+	}
+	if p, ok := graphPercentiles[stat]; ok {
+		return graphDataPercentile(s, start, end, group, p)
+	}
 	var d []DateScan
-	model := service.(*Service).HitsBetween(start, end, group)
+	model := s.HitsBetween(start, end, group)
 	rows, _ := model.Rows()
 	for rows.Next() {
 		var gd DateScan
@@ -222,6 +254,46 @@ func GraphDataRaw(service types.ServiceInterface, start, end time.Time, group st
 	return &DateScanObj{d}
 }
 
+// graphDataPercentile returns a latency percentile of the service's hits between two times for each group
+func graphDataPercentile(s *Service, start, end time.Time, group string, p float64) *DateScanObj {
+	var hits []*types.Hit
+	err := hitsDB().Select("created_at, latency").Where("service = ? AND created_at BETWEEN ? AND ?", s.Id, start.Format(types.TIME_DAY), end.Format(types.TIME_DAY)).Order("created_at asc").Find(&hits)
+	if err.Error != nil {
+		utils.Log(2, err.Error)
+		return &DateScanObj{}
+	}
+	period := time.Duration(groupSeconds(group)) * time.Second
+	return &DateScanObj{hitsScan(hits, period, p)}
+}
+
+// hitsScan returns the average latency of the hits in each period, or the latency percentile p if p is more than 0.
+// The hits must be in order.
+func hitsScan(hits []*types.Hit, period time.Duration, p float64) []DateScan {
+	var d []DateScan
+	var timeframe time.Time
+	var latencies []float64
+	var sum float64
+	for i, h := range hits {
+		latencies = append(latencies, h.Latency)
+		sum += h.Latency
+		timeframe = h.CreatedAt.UTC().Truncate(period)
+		if i+1 < len(hits) && hits[i+1].CreatedAt.UTC().Truncate(period).Equal(timeframe) {
+			continue
+		}
+		value := sum / float64(len(latencies))
+		if p > 0 {
+			value = percentile(latencies, p)
+		}
+		d = append(d, DateScan{
+			CreatedAt: utils.Timezoner(timeframe, CoreApp.Timezone).Format(types.TIME),
+			Value:     int64(value * 1000),
+		})
+		latencies = nil
+		sum = 0
+	}
+	return d
+}
+
 func (d *DateScanObj) ToString() string {
 	data, err := json.Marshal(d.Array)
 	if err != nil {
@@ -235,7 +307,7 @@ func (d *DateScanObj) ToString() string {
 func (s *Service) GraphData() string {
 	start := time.Now().Add((-24 * 7) * time.Hour)
 	end := time.Now()
-	obj := GraphDataRaw(s, start, end, "hour")
+	obj := GraphDataRaw(s, start, end, "hour", "avg")
 	data, err := json.Marshal(obj)
 	if err != nil {
 		utils.Log(2, err)
@@ -252,11 +324,11 @@ func (s *Service) AvgUptime24() string {
 
 // AvgUptime returns average online status for last 24 hours
 func (s *Service) AvgUptime(ago time.Time) string {
-	failed, _ := s.TotalFailuresSince(ago)
+	failed, _ := s.uptimeFailuresSince(ago)
 	if failed == 0 {
 		return "100"
 	}
-	total, _ := s.TotalHitsSince(ago)
+	total, _ := s.uptimeHitsSince(ago)
 	if total == 0 {
 		return "0.00"
 	}
@@ -274,8 +346,8 @@ func (s *Service) AvgUptime(ago time.Time) string {
 
 // TotalUptime returns the total uptime percent of a service
 func (s *Service) TotalUptime() string {
-	hits, _ := s.TotalHits()
-	failures, _ := s.TotalFailures()
+	hits, _ := s.uptimeHitsSince(time.Time{})
+	failures, _ := s.uptimeFailuresSince(time.Time{})
 	percent := float64(failures) / float64(hits) * 100
 	percent = 100 - percent
 	if percent < 0 {
@@ -313,6 +385,7 @@ func (u *Service) Delete() error {
 		return err.Error
 	}
 	u.Close()
+	u.closeCheckins()
 	slice := CoreApp.Services
 	CoreApp.Services = append(slice[:i], slice[i+1:]...)
 	reorderServices()
@@ -320,14 +393,99 @@ func (u *Service) Delete() error {
 	return err.Error
 }
 
+// Pause will stop checking the service until it's resumed
+func (u *Service) Pause() error {
+	return u.setPaused(true)
+}
+
+// Resume will start checking a paused service again
+func (u *Service) Resume() error {
+	return u.setPaused(false)
+}
+
+// setPaused will save the service's paused state, the checking routine skips paused services
+func (u *Service) setPaused(paused bool) error {
+	err := servicesDB().Where("id = ?", u.Id).UpdateColumn("paused", paused)
+	if err.Error != nil {
+		utils.Log(3, fmt.Sprintf("Failed to pause service %v. %v", u.Name, err.Error))
+		return err.Error
+	}
+	u.Paused = paused
+	if paused {
+		utils.Log(1, fmt.Sprintf("Service %v has been paused", u.Name))
+	} else {
+		utils.Log(1, fmt.Sprintf("Service %v has been resumed", u.Name))
+	}
+	return nil
+}
+
 // UpdateSingle will update a single column for a service
 func (u *Service) UpdateSingle(attr ...interface{}) error {
 	return servicesDB().Model(u).Update(attr).Error
 }
 
+// normalize will set the defaults for settings that are not set or out of range, every service is normalized
+// when it's loaded from the database and before it's created or updated from the dashboard or the API
+func (u *Service) normalize() {
+	if u.Type == "http" && u.ExpectedStatus == 0 {
+		u.ExpectedStatus = 200
+	}
+	if u.Timeout < 1 {
+		u.Timeout = int(defaultTimeout / time.Second)
+	}
+	if u.FailAfter < 1 {
+		u.FailAfter = 1
+	}
+	if u.RecoverAfter < 1 {
+		u.RecoverAfter = 1
+	}
+	if u.Quorum < 1 {
+		u.Quorum = 1
+	}
+	if u.Type == "icmp" && u.PacketCount == 0 {
+		u.PacketCount = 3
+	}
+	if u.Type == "dns" && u.DnsRecord == "" {
+		u.DnsRecord = "A"
+	}
+	if u.SloWindow < 1 {
+		u.SloWindow = 30
+	}
+	if u.SloBurnRate <= 0 {
+		u.SloBurnRate = 14.4
+	}
+}
+
 // Update will update a service in the database, the service's checking routine can be restarted by passing true
 func (u *Service) Update(restart bool) error {
+	u.normalize()
 	err := servicesDB().Update(u)
+	if err.Error == nil {
+		// zero values are skipped when updating from the struct, columns that can be cleared or turned off are saved here
+		err = servicesDB().Where("id = ?", u.Id).UpdateColumns(map[string]interface{}{
+			"packet_count":       u.PacketCount,
+			"max_packet_loss":    u.MaxPacketLoss,
+			"dns_record":         u.DnsRecord,
+			"dns_resolver":       u.DnsResolver,
+			"cert_expiry_warn":   u.CertExpiryWarn,
+			"method":             u.Method,
+			"headers":            u.Headers,
+			"content_type":       u.ContentType,
+			"skip_verify":        u.SkipVerify,
+			"no_redirects":       u.NoRedirects,
+			"grpc_service":       u.GrpcService,
+			"grpc_tls":           u.GrpcTls,
+			"retries":            u.Retries,
+			"retry_delay":        u.RetryDelay,
+			"group_name":         u.GroupName,
+			"parent_id":          u.ParentId,
+			"slo_target":         u.SloTarget,
+			"slo_latency":        u.SloLatency,
+			"hits_retention":     u.HitsRetention,
+			"failures_retention": u.FailuresRetention,
+			"paused":             u.Paused,
+		})
+	}
 	if err.Error != nil {
 		utils.Log(3, fmt.Sprintf("Failed to update service %v. %v", u.Name, err))
 		return err.Error
@@ -346,6 +504,7 @@ func (u *Service) Update(restart bool) error {
 
 // Create will create a service and insert it into the database
 func (u *Service) Create(check bool) (int64, error) {
+	u.normalize()
 	u.CreatedAt = time.Now()
 	db := servicesDB().Create(u)
 	if db.Error != nil {
@@ -16,8 +16,21 @@
 package core
 
 import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
 	"github.com/hunterlong/statup/types"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/icmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -26,6 +39,19 @@ var (
 	newServiceId int64
 )
 
+// createTestService creates a TCP service that is never checked, for tests that record its hits and failures
+func createTestService(t *testing.T, service *types.Service) *Service {
+	service.Domain = "127.0.0.1"
+	service.Port = 1
+	service.Type = "tcp"
+	service.Interval = 3600
+	service.Timeout = 1
+	s := ReturnService(service)
+	_, err := s.Create(false)
+	assert.Nil(t, err)
+	return s
+}
+
 func TestSelectHTTPService(t *testing.T) {
 	services, err := CoreApp.SelectAllServices()
 	assert.Nil(t, err)
@@ -340,3 +366,1028 @@ func TestDNScheckService(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotZero(t, amount)
 }
+
+func TestServiceIcmpCheck(t *testing.T) {
+	conn, err := icmp.ListenPacket("udp4", "")
+	if err != nil {
+		conn, err = icmp.ListenPacket("ip4:icmp", "")
+	}
+	if err != nil {
+		t.Skipf("ICMP sockets are not permitted: %v", err)
+	}
+	conn.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "Localhost Ping"
+	s.Domain = "127.0.0.1"
+	s.Type = "icmp"
+	s.PacketCount = 2
+	s.Timeout = 5
+	s.Check(false)
+	assert.NotZero(t, s.Latency)
+}
+
+func TestServiceFailedIcmpCheck(t *testing.T) {
+	s := ReturnService(new(types.Service))
+	s.Name = "Bad Ping"
+	s.Domain = "thisdomainisfakeanditsgoingtofail.com"
+	s.Type = "icmp"
+	s.Timeout = 5
+	s.Check(true)
+	assert.False(t, s.Online)
+}
+
+func TestServiceUdpCheck(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		n, addr, err := conn.ReadFrom(buf)
+		if err == nil {
+			conn.WriteTo(append([]byte("pong "), buf[:n]...), addr)
+		}
+	}()
+	s := ReturnService(new(types.Service))
+	s.Name = "UDP Echo"
+	s.Domain = "127.0.0.1"
+	s.Port = conn.LocalAddr().(*net.UDPAddr).Port
+	s.Type = "udp"
+	s.PostData = `ping\x00`
+	s.Expected = "^pong ping\\x00$"
+	s.Timeout = 1
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.Equal(t, "pong ping\x00", s.LastResponse)
+	assert.NotZero(t, s.Latency)
+}
+
+func TestServiceFailedUdpCheck(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "UDP Silent"
+	s.Domain = "127.0.0.1"
+	s.Port = conn.LocalAddr().(*net.UDPAddr).Port
+	s.Type = "udp"
+	s.PostData = "ping"
+	s.Timeout = 1
+	s.Check(true)
+	assert.False(t, s.Online)
+}
+
+func TestServiceDnsCheck(t *testing.T) {
+	s := ReturnService(new(types.Service))
+	s.Name = "Localhost DNS"
+	s.Domain = "localhost"
+	s.Type = "dns"
+	s.DnsRecord = "A"
+	s.Expected = "^127\\.0\\.0\\.1$"
+	s.Timeout = 5
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.Equal(t, "127.0.0.1", s.LastResponse)
+}
+
+func TestServiceFailedDnsCheck(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "Silent DNS"
+	s.Domain = "statup.io"
+	s.Type = "dns"
+	s.DnsRecord = "TXT"
+	s.DnsResolver = conn.LocalAddr().String()
+	s.Timeout = 1
+	s.Check(true)
+	assert.False(t, s.Online)
+}
+
+func TestServiceTlsCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	assert.Nil(t, err)
+	defer conn.Close()
+	state := conn.ConnectionState()
+	s := ReturnService(new(types.Service))
+	s.Name = "TLS Service"
+	s.tlsCertificate(&state)
+	assert.Equal(t, server.Certificate().NotAfter, s.CertExpiry)
+	assert.Contains(t, s.CertDNSNames, "example.com")
+	assert.NotEmpty(t, s.CertIssuer)
+	assert.Contains(t, s.CertExpiryText(), "TLS Certificate expires on")
+}
+
+func TestServiceTlsCertificateCleared(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "Moved From TLS"
+	s.Domain = tlsServer.URL
+	s.Type = "http"
+	s.Method = "GET"
+	s.ExpectedStatus = 200
+	s.SkipVerify = true
+	s.Timeout = 5
+	s.CertExpiryWarn = 365 * 100
+	s.Check(true)
+	assert.False(t, s.Online)
+	assert.False(t, s.CertExpiry.IsZero())
+
+	s.Domain = server.URL
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.True(t, s.CertExpiry.IsZero())
+	assert.Empty(t, s.CertIssuer)
+	assert.Empty(t, s.CertDNSNames)
+}
+
+func TestServiceHttpRequestOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%v %v %v %v", r.Method, r.Header.Get("Authorization"), r.Header.Get("Content-Type"), string(body))
+	}))
+	defer server.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "HTTP Options"
+	s.Domain = server.URL
+	s.Type = "http"
+	s.Method = "PUT"
+	s.PostData = "name=statup"
+	s.ContentType = "application/x-www-form-urlencoded"
+	s.Headers = "Authorization: Bearer secret\nX-Empty"
+	s.ExpectedStatus = 200
+	s.Timeout = 5
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.Equal(t, "PUT Bearer secret application/x-www-form-urlencoded name=statup", s.LastResponse)
+}
+
+func TestServiceHttpNoRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer server.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "HTTP Redirect"
+	s.Domain = server.URL
+	s.Type = "http"
+	s.Method = "HEAD"
+	s.NoRedirects = true
+	s.ExpectedStatus = 302
+	s.Timeout = 5
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.Equal(t, 302, s.LastStatusCode)
+}
+
+func TestServiceHttpSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "HTTP Self Signed"
+	s.Domain = server.URL
+	s.Type = "http"
+	s.ExpectedStatus = 200
+	s.Timeout = 5
+	s.Check(true)
+	assert.False(t, s.Online)
+	s.SkipVerify = true
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.Equal(t, server.Certificate().NotAfter, s.CertExpiry)
+}
+
+func TestServiceGrpcCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("statup", grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("offline", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	s := ReturnService(new(types.Service))
+	s.Name = "gRPC Service"
+	s.Domain = "127.0.0.1"
+	s.Port = listener.Addr().(*net.TCPAddr).Port
+	s.Type = "grpc"
+	s.GrpcService = "statup"
+	s.Timeout = 5
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.Equal(t, "SERVING", s.LastResponse)
+	assert.NotZero(t, s.Latency)
+
+	s.GrpcService = "offline"
+	s.Check(true)
+	assert.False(t, s.Online)
+	assert.Equal(t, "NOT_SERVING", s.LastResponse)
+}
+
+func TestServiceChecksDefaultTimeout(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpServer.Close()
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer tcpListener.Close()
+	go func() {
+		for {
+			conn, err := tcpListener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer udpConn.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := udpConn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			udpConn.WriteTo(buf[:n], addr)
+		}
+	}()
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	grpcServer := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("statup", grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+	go grpcServer.Serve(grpcListener)
+	defer grpcServer.Stop()
+
+	// services created with the API don't have a timeout, normalize sets the default timeout
+	services := []*types.Service{
+		{Name: "HTTP Default Timeout", Type: "http", Domain: httpServer.URL, Method: "GET"},
+		{Name: "TCP Default Timeout", Type: "tcp", Domain: "127.0.0.1", Port: tcpListener.Addr().(*net.TCPAddr).Port},
+		{Name: "UDP Default Timeout", Type: "udp", Domain: "127.0.0.1", Port: udpConn.LocalAddr().(*net.UDPAddr).Port, PostData: "ping"},
+		{Name: "DNS Default Timeout", Type: "dns", Domain: "localhost", Expected: "^127\\.0\\.0\\.1$"},
+		{Name: "gRPC Default Timeout", Type: "grpc", Domain: "127.0.0.1", Port: grpcListener.Addr().(*net.TCPAddr).Port, GrpcService: "statup"},
+	}
+	if conn, err := icmp.ListenPacket("udp4", ""); err == nil {
+		conn.Close()
+		services = append(services, &types.Service{Name: "ICMP Default Timeout", Type: "icmp", Domain: "127.0.0.1"})
+	}
+	for _, service := range services {
+		s := ReturnService(service)
+		s.normalize()
+		assert.Equal(t, 30, s.Timeout)
+		s.Check(true)
+		assert.True(t, s.Online, s.Name)
+	}
+}
+
+func TestServiceFailAfterThreshold(t *testing.T) {
+	s := ReturnService(new(types.Service))
+	s.Name = "Flapping Service"
+	s.FailAfter = 3
+	s.RecoverAfter = 2
+	recordSuccess(s)
+	assert.True(t, s.Online)
+	recordFailure(s, "first failure")
+	assert.True(t, s.Online)
+	recordSuccess(s)
+	recordFailure(s, "first failure again")
+	recordFailure(s, "second failure")
+	assert.True(t, s.Online)
+	recordFailure(s, "third failure")
+	assert.False(t, s.Online)
+	assert.Equal(t, 3, s.FailureStreak)
+	recordSuccess(s)
+	assert.False(t, s.Online)
+	recordSuccess(s)
+	assert.True(t, s.Online)
+	assert.Equal(t, 2, s.SuccessStreak)
+}
+
+func TestServiceDownSince(t *testing.T) {
+	s := ReturnService(new(types.Service))
+	s.Name = "Outage Service"
+	s.FailAfter = 2
+	recordSuccess(s)
+	assert.True(t, s.DownSince.IsZero())
+	recordFailure(s, "first failure")
+	downSince := s.DownSince
+	assert.False(t, downSince.IsZero())
+	recordFailure(s, "second failure")
+	assert.False(t, s.Online)
+	assert.Equal(t, downSince, s.DownSince)
+	recordSuccess(s)
+	assert.True(t, s.Online)
+}
+
+func TestServiceCheckRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	s := ReturnService(new(types.Service))
+	s.Name = "Retried Service"
+	s.Domain = server.URL
+	s.Type = "http"
+	s.ExpectedStatus = 200
+	s.Timeout = 5
+	s.Retries = 2
+	s.RetryDelay = 10
+	s.Check(true)
+	assert.True(t, s.Online)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, 2, s.Attempt)
+}
+
+func TestServiceCheckRetriesFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	s := createTestService(t, &types.Service{Name: "Retried TCP Service"})
+	s.Port = port
+	s.Type = "tcp"
+	s.Timeout = 1
+	s.Retries = 2
+	s.RetryDelay = 10
+	s.Check(true)
+	assert.False(t, s.Online)
+	assert.Equal(t, 3, s.Attempt)
+	failures := s.LimitedFailures()
+	assert.NotEmpty(t, failures)
+	assert.Equal(t, 3, failures[0].Attempts)
+}
+
+func TestServiceProbeQuorum(t *testing.T) {
+	s := ReturnService(new(types.Service))
+	s.Name = "Multi Location Service"
+	s.Quorum = 2
+	s.Latency = 0.1
+	recordSuccess(s)
+	assert.True(t, s.Online)
+	s.RecordProbe(&types.ProbeResult{Location: "us-east", Online: false, Issue: "probe failure"})
+	assert.True(t, s.Online)
+	assert.False(t, s.Locations["us-east"].Online)
+	recordFailure(s, "local failure")
+	assert.False(t, s.Online)
+	s.RecordProbe(&types.ProbeResult{Location: "us-east", Online: true, Latency: 0.2})
+	assert.True(t, s.Online)
+	assert.True(t, s.Locations["us-east"].Online)
+}
+
+func TestServiceProbeStaleLocation(t *testing.T) {
+	s := ReturnService(new(types.Service))
+	s.Name = "Stale Location Service"
+	s.Interval = 60
+	s.Quorum = 2
+	s.RecordProbe(&types.ProbeResult{Location: "us-east", Online: false, Issue: "probe failure"})
+	assert.Equal(t, 1, s.locationsDown())
+	s.Locations["us-east"].LastSeen = time.Now().Add(-s.locationExpiry() - time.Second)
+	assert.Equal(t, 0, s.locationsDown())
+	assert.NotContains(t, s.Locations, "us-east")
+	assert.Equal(t, 1, s.setLocation("", false))
+}
+
+func TestProberReport(t *testing.T) {
+	var result types.ProbeResult
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/services":
+			fmt.Fprint(w, `[{"id": 7, "name": "Probe Service", "type": "tcp", "domain": "127.0.0.1", "port": 1, "timeout": 1}]`)
+		case "/api/services/7/probe":
+			json.NewDecoder(r.Body).Decode(&result)
+		}
+	}))
+	defer server.Close()
+	prober := NewProber(server.URL+"/", "secret", "eu-west")
+	services, err := prober.Services()
+	assert.Nil(t, err)
+	assert.Len(t, services, 1)
+	Probe = prober
+	defer func() { Probe = nil }()
+	services[0].Check(true)
+	assert.False(t, services[0].Online)
+	assert.Equal(t, "eu-west", result.Location)
+	assert.False(t, result.Online)
+	assert.Contains(t, result.Issue, "TCP Dial Error")
+}
+
+func TestProberRefresh(t *testing.T) {
+	services := `[{"id": 7, "name": "Probe Service", "type": "tcp", "domain": "127.0.0.1", "port": 1, "timeout": 1, "check_interval": 3600, "updated_at": "2018-10-01T00:00:00Z"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/services" {
+			fmt.Fprint(w, services)
+		}
+	}))
+	defer server.Close()
+	prober := NewProber(server.URL, "secret", "eu-west")
+	Probe = prober
+	defer func() { Probe = nil }()
+	assert.Nil(t, prober.refresh())
+	assert.Len(t, prober.services, 1)
+	first := prober.services[0]
+	assert.True(t, first.IsRunning())
+
+	assert.Nil(t, prober.refresh())
+	assert.Len(t, prober.services, 1)
+	assert.True(t, first == prober.services[0])
+	assert.True(t, first.IsRunning())
+
+	services = `[{"id": 7, "name": "Probe Service", "type": "tcp", "domain": "127.0.0.1", "port": 2, "timeout": 1, "check_interval": 3600, "updated_at": "2018-10-02T00:00:00Z"}]`
+	assert.Nil(t, prober.refresh())
+	assert.Len(t, prober.services, 1)
+	updated := prober.services[0]
+	assert.False(t, first == updated)
+	assert.False(t, first.IsRunning())
+	assert.True(t, updated.IsRunning())
+	assert.Equal(t, 2, updated.Port)
+
+	services = `[]`
+	assert.Nil(t, prober.refresh())
+	assert.Empty(t, prober.services)
+	assert.False(t, updated.IsRunning())
+}
+
+func TestServiceCheckin(t *testing.T) {
+	// check-ins aren't checked from a location, a missed check-in doesn't need a quorum of locations
+	service := createTestService(t, &types.Service{Name: "Cron Job", Quorum: 2})
+	serviceId := service.Id
+	checkin := ReturnCheckin(&types.Checkin{
+		Service:     serviceId,
+		Interval:    1,
+		GracePeriod: 1,
+		Api:         "cronjobcheckin",
+	})
+	id, err := checkin.Create()
+	assert.Nil(t, err)
+	assert.NotZero(t, id)
+	checkin.Close()
+	assert.Equal(t, id, FindCheckin("cronjobcheckin").Id)
+	assert.False(t, checkin.RecheckCheckinFailure())
+	checkin.CreatedAt = time.Now().Add(-5 * time.Second)
+	assert.True(t, checkin.RecheckCheckinFailure())
+	assert.True(t, checkin.Failing)
+	assert.False(t, service.Online)
+	failures := service.LimitedFailures()
+	assert.NotEmpty(t, failures)
+	assert.Contains(t, failures[0].Issue, "never received")
+	assert.Empty(t, failures[0].Location)
+	checkin.Receivehit()
+	assert.False(t, checkin.Failing)
+	assert.True(t, service.Online)
+	hits, err := service.TotalHits()
+	assert.Nil(t, err)
+	assert.Zero(t, hits)
+	assert.Equal(t, int64(1), checkin.Hits)
+	assert.False(t, checkin.RecheckCheckinFailure())
+}
+
+func TestServiceCheckinFailingCheck(t *testing.T) {
+	service := createTestService(t, &types.Service{Name: "Nightly Backup"})
+	checkin := ReturnCheckin(&types.Checkin{
+		Service:     service.Id,
+		Interval:    3600,
+		GracePeriod: 60,
+		Api:         "nightlybackupcheckin",
+	})
+	_, err := checkin.Create()
+	assert.Nil(t, err)
+	checkin.Close()
+	checkin.ReceiveFailure("backup failed")
+	assert.False(t, service.Online)
+	service.addHit(&types.Hit{Service: service.Id, Latency: 0.1, CreatedAt: time.Now()})
+	assert.False(t, service.Online)
+	service.addFailure(&types.Failure{Service: service.Id, Issue: "connection refused", CreatedAt: time.Now()})
+	checkin.Receivehit()
+	assert.False(t, service.Online)
+	service.addHit(&types.Hit{Service: service.Id, Latency: 0.1, CreatedAt: time.Now()})
+	assert.True(t, service.Online)
+}
+
+func TestCheckinSchedule(t *testing.T) {
+	checkin := ReturnCheckin(&types.Checkin{
+		Schedule:    "0 3 * * 1-5",
+		Timezone:    "America/New_York",
+		GracePeriod: 60,
+	})
+	loc, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	checkin.Last = time.Date(2018, 10, 5, 3, 1, 0, 0, loc)
+	expected := checkin.Expected()
+	assert.Equal(t, time.Date(2018, 10, 8, 3, 1, 0, 0, loc).Unix(), expected.Unix())
+	assert.Equal(t, "on schedule 0 3 * * 1-5 (America/New_York)", checkin.ScheduleText())
+}
+
+func TestCheckinInvalidSchedule(t *testing.T) {
+	checkin := ReturnCheckin(&types.Checkin{
+		Service:  newServiceId,
+		Schedule: "not a schedule",
+		Api:      "invalidschedule",
+	})
+	_, err := checkin.Create()
+	assert.NotNil(t, err)
+	checkin = ReturnCheckin(&types.Checkin{
+		Service:  newServiceId,
+		Schedule: "0 3 * * *",
+		Timezone: "Not/A_Timezone",
+		Api:      "invalidtimezone",
+	})
+	_, err = checkin.Create()
+	assert.NotNil(t, err)
+}
+
+func TestCheckinStartAndFail(t *testing.T) {
+	checkin := FindCheckin("cronjobcheckin")
+	assert.NotNil(t, checkin)
+	checkin.ReceiveStart()
+	assert.False(t, checkin.Started.IsZero())
+	checkin.ReceiveFailure("backup disk is full")
+	assert.True(t, checkin.Failing)
+	assert.True(t, checkin.Started.IsZero())
+	history := checkin.History()
+	assert.True(t, len(history) >= 3)
+	assert.Equal(t, "fail", history[0].Status)
+	assert.Equal(t, "backup disk is full", history[0].Message)
+	assert.Equal(t, "start", history[1].Status)
+	service := SelectService(checkin.Service)
+	assert.False(t, service.Online)
+	assert.Contains(t, service.LimitedFailures()[0].Issue, "backup disk is full")
+	checkin.Receivehit()
+	assert.True(t, service.Online)
+	assert.Equal(t, "success", checkin.History()[0].Status)
+}
+
+func TestMaintenanceActiveAt(t *testing.T) {
+	start := time.Date(2018, 10, 1, 2, 0, 0, 0, time.UTC)
+	once := ReturnMaintenance(&types.Maintenance{Start: start, End: start.Add(time.Hour)})
+	assert.False(t, once.ActiveAt(start.Add(-time.Minute)))
+	assert.True(t, once.ActiveAt(start.Add(30*time.Minute)))
+	assert.False(t, once.ActiveAt(start.Add(24*time.Hour)))
+	weekly := ReturnMaintenance(&types.Maintenance{Start: start, End: start.Add(time.Hour), Recurring: "weekly"})
+	assert.True(t, weekly.ActiveAt(start.Add(7*24*time.Hour+30*time.Minute)))
+	assert.False(t, weekly.ActiveAt(start.Add(24*time.Hour+30*time.Minute)))
+	daily := ReturnMaintenance(&types.Maintenance{Start: start, End: start.Add(25 * time.Hour), Recurring: "daily"})
+	_, err := daily.Create()
+	assert.NotNil(t, err)
+}
+
+func TestServiceMaintenance(t *testing.T) {
+	service := createTestService(t, &types.Service{Name: "Deploying Service"})
+	recordSuccess(service)
+	assert.True(t, service.Online)
+	maintenance := ReturnMaintenance(&types.Maintenance{
+		Service: service.Id,
+		Message: "Deploying a new version",
+		Start:   time.Now().Add(-time.Minute),
+		End:     time.Now().Add(time.Hour),
+	})
+	id, err := maintenance.Create()
+	assert.Nil(t, err)
+	assert.NotZero(t, id)
+	assert.NotNil(t, service.UnderMaintenance())
+	recordFailure(service, "failure during deploy")
+	assert.False(t, service.Online)
+	assert.False(t, service.DownNotified)
+	assert.True(t, service.LimitedFailures()[0].Maintenance)
+	assert.Equal(t, "100", service.AvgUptime24())
+	err = maintenance.Delete()
+	assert.Nil(t, err)
+	assert.Nil(t, service.UnderMaintenance())
+	recordFailure(service, "failure after deploy")
+	assert.True(t, service.DownNotified)
+	assert.False(t, service.LimitedFailures()[0].Maintenance)
+	recordSuccess(service)
+	assert.True(t, service.Online)
+	assert.False(t, service.DownNotified)
+}
+
+func TestIncidents(t *testing.T) {
+	incident := ReturnIncident(&types.Incident{
+		Title:    "Elevated error rates",
+		Services: []int64{newServiceId},
+	})
+	id, err := incident.Create()
+	assert.Nil(t, err)
+	assert.NotZero(t, id)
+	assert.Equal(t, "investigating", incident.Status)
+	_, err = incident.AddUpdate(&types.IncidentUpdate{Status: "identified", Message: "A bad deploy has been rolled back"})
+	assert.Nil(t, err)
+	_, err = incident.AddUpdate(&types.IncidentUpdate{Status: "fixed", Message: "not a status"})
+	assert.NotNil(t, err)
+	incident, err = SelectIncident(id)
+	assert.Nil(t, err)
+	assert.Equal(t, "identified", incident.Status)
+	assert.True(t, incident.Affects(newServiceId))
+	assert.Len(t, incident.Updates, 1)
+	assert.Equal(t, "A bad deploy has been rolled back", incident.Updates[0].Message)
+	assert.NotEmpty(t, CoreApp.Incidents())
+	_, err = incident.AddUpdate(&types.IncidentUpdate{Status: "resolved", Message: "All services are operating normally"})
+	assert.Nil(t, err)
+	assert.True(t, incident.Resolved())
+	assert.Len(t, incident.Updates, 2)
+	assert.False(t, incident.Acknowledged())
+	err = incident.Acknowledge("hunter")
+	assert.Nil(t, err)
+	incident, err = SelectIncident(id)
+	assert.Nil(t, err)
+	assert.True(t, incident.Acknowledged())
+	assert.Equal(t, "hunter", incident.AcknowledgedBy)
+	err = incident.Delete()
+	assert.Nil(t, err)
+	_, err = SelectIncident(id)
+	assert.NotNil(t, err)
+}
+
+func TestCreateIncidentInvalid(t *testing.T) {
+	incident := ReturnIncident(&types.Incident{Title: "Broken", Status: "down"})
+	_, err := incident.Create()
+	assert.NotNil(t, err)
+	incident = ReturnIncident(&types.Incident{Title: " "})
+	_, err = incident.Create()
+	assert.NotNil(t, err)
+}
+
+func TestServiceDependency(t *testing.T) {
+	parent := createTestService(t, &types.Service{
+		Name:      "Database",
+		GroupName: "Payments",
+	})
+	parentId := parent.Id
+	child := createTestService(t, &types.Service{
+		Name:      "Payments API",
+		GroupName: "Payments",
+		ParentId:  parentId,
+	})
+	childId := child.Id
+	assert.Equal(t, parentId, child.Parent().Id)
+	assert.Len(t, parent.Children(), 1)
+	assert.False(t, parent.ValidParent(childId))
+	assert.True(t, child.ValidParent(0))
+
+	recordSuccess(parent)
+	recordSuccess(child)
+	recordFailure(parent, "database is down")
+	assert.False(t, parent.Online)
+	assert.True(t, parent.DownNotified)
+	recordFailure(child, "payments api is down")
+	assert.False(t, child.Online)
+	assert.False(t, child.DownNotified)
+	failure := child.LimitedFailures()[0]
+	assert.True(t, failure.DependencyDown)
+	assert.Equal(t, "Dependency Down", failure.ParseError())
+
+	var group *ServiceGroup
+	for _, g := range CoreApp.ServiceGroups() {
+		if g.Name == "Payments" {
+			group = g
+		}
+	}
+	assert.NotNil(t, group)
+	assert.Len(t, group.Services, 2)
+	assert.Equal(t, "offline", group.Status())
+	recordSuccess(parent)
+	assert.Equal(t, "degraded", group.Status())
+	assert.Contains(t, CoreApp.GroupNames(), "Payments")
+	recordFailure(child, "payments api is still down")
+	assert.True(t, child.DownNotified)
+	assert.False(t, child.LimitedFailures()[0].DependencyDown)
+}
+
+func TestServiceSLO(t *testing.T) {
+	service := createTestService(t, &types.Service{
+		Name:        "Checkout",
+		SloTarget:   90,
+		SloWindow:   30,
+		SloLatency:  300,
+		SloBurnRate: 0.5,
+	})
+	assert.True(t, service.HasSLO())
+	service.Latency = 0.1
+	for i := 0; i < 9; i++ {
+		recordSuccess(service)
+	}
+	service.Latency = 0.5
+	recordSuccess(service)
+	slo := service.SLO()
+	assert.Equal(t, float64(100), slo.Uptime)
+	assert.Equal(t, float64(100), slo.BudgetRemaining)
+	assert.Equal(t, float64(10), slo.ErrorBudget)
+	assert.Equal(t, float64(500), slo.LatencyP95)
+	assert.False(t, slo.LatencyMet)
+	assert.False(t, service.BudgetAlerted)
+
+	recordFailure(service, "checkout is down")
+	slo = service.SLO()
+	assert.Equal(t, uint64(10), slo.Hits)
+	assert.Equal(t, uint64(1), slo.Failures)
+	assert.Equal(t, 90.91, slo.Uptime)
+	assert.Equal(t, 9.09, slo.BudgetRemaining)
+	assert.Equal(t, 0.91, slo.BurnRate)
+	assert.True(t, service.BudgetAlerted)
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, float64(0), percentile(nil, 95))
+	assert.Equal(t, float64(5), percentile([]float64{5}, 95))
+	assert.Equal(t, float64(50), percentile([]float64{100, 1, 50, 95, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17}, 90))
+}
+
+func TestServiceOutages(t *testing.T) {
+	service := createTestService(t, &types.Service{Name: "Outage Service"})
+	assert.Empty(t, service.Outages(10))
+	start := time.Now().Add(-time.Hour)
+	service.CreateHit(&types.Hit{Service: service.Id, CreatedAt: start})
+	service.CreateFailure(&types.Failure{Issue: "connection refused", CreatedAt: start.Add(1 * time.Minute)})
+	service.CreateFailure(&types.Failure{Issue: "i/o timeout", CreatedAt: start.Add(2 * time.Minute)})
+	service.CreateHit(&types.Hit{Service: service.Id, CreatedAt: start.Add(6 * time.Minute)})
+	service.CreateHit(&types.Hit{Service: service.Id, CreatedAt: start.Add(7 * time.Minute)})
+	service.CreateFailure(&types.Failure{Issue: "no such host", CreatedAt: start.Add(50 * time.Minute)})
+	assert.NotZero(t, service.Downtime())
+
+	outages := service.Outages(10)
+	assert.Len(t, outages, 2)
+	assert.True(t, outages[0].Ongoing)
+	assert.Equal(t, "Domain is offline or not found", outages[0].Reason)
+	assert.Equal(t, uint64(1), outages[0].Failures)
+	assert.NotNil(t, service.CurrentOutage())
+	assert.False(t, outages[1].Ongoing)
+	assert.Equal(t, "Connection Failed", outages[1].Reason)
+	assert.Equal(t, "connection refused", outages[1].Issue)
+	assert.Equal(t, uint64(2), outages[1].Failures)
+	assert.Equal(t, int64(300), outages[1].Duration)
+	assert.Equal(t, "5 minutes", outages[1].DowntimeText())
+	assert.Len(t, service.Outages(1), 1)
+
+	service.CreateHit(&types.Hit{Service: service.Id, CreatedAt: start.Add(55 * time.Minute)})
+	assert.Nil(t, service.CurrentOutage())
+	assert.Zero(t, service.Downtime())
+}
+
+func TestServiceHttpTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+	service := ReturnService(&types.Service{
+		Name:           "Timed Service",
+		Domain:         server.URL,
+		Type:           "http",
+		Method:         "GET",
+		ExpectedStatus: 200,
+		SkipVerify:     true,
+		Interval:       3600,
+		Timeout:        5,
+	})
+	_, err := service.Create(false)
+	assert.Nil(t, err)
+	service.Check(true)
+	assert.True(t, service.Online)
+	assert.True(t, service.ConnectTime > 0)
+	assert.True(t, service.TLSHandshake > 0)
+	assert.True(t, service.FirstByte >= 0.01)
+	assert.Contains(t, service.TimingText(), "First Byte")
+	hits, err := service.Hits()
+	assert.Nil(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, service.FirstByte, hits[0].FirstByte)
+	assert.Equal(t, service.TLSHandshake, hits[0].TLSHandshake)
+}
+
+func TestGraphDataPercentile(t *testing.T) {
+	service := createTestService(t, &types.Service{Name: "Percentile Service"})
+	day := time.Now().Add(-48 * time.Hour).UTC().Truncate(24 * time.Hour)
+	for i := 1; i <= 100; i++ {
+		service.CreateHit(&types.Hit{Service: service.Id, Latency: float64(i) / 1000, CreatedAt: day.Add(time.Duration(i) * time.Second)})
+	}
+	service.CreateHit(&types.Hit{Service: service.Id, Latency: 1, CreatedAt: day.Add(2 * time.Hour)})
+	start := day.Add(-24 * time.Hour)
+	end := time.Now()
+	p95 := GraphDataRaw(service, start, end, "hour", "p95")
+	assert.Len(t, p95.Array, 2)
+	assert.Equal(t, int64(95), p95.Array[0].Value)
+	assert.Equal(t, int64(1000), p95.Array[1].Value)
+	p50 := GraphDataRaw(service, start, end, "hour", "p50")
+	assert.Equal(t, int64(50), p50.Array[0].Value)
+	avg := GraphDataRaw(service, start, end, "hour", "avg")
+	assert.Len(t, avg.Array, 2)
+}
+
+func TestServiceRollups(t *testing.T) {
+	service := createTestService(t, &types.Service{Name: "Rollup Service"})
+	day := time.Now().UTC().Add(-10 * 24 * time.Hour).Truncate(24 * time.Hour)
+	for i := 1; i <= 10; i++ {
+		service.CreateHit(&types.Hit{Service: service.Id, Latency: float64(i) / 100, CreatedAt: day.Add(time.Duration(i) * time.Minute)})
+	}
+	service.CreateFailure(&types.Failure{Issue: "connection refused", CreatedAt: day.Add(30 * time.Minute)})
+	service.CreateHit(&types.Hit{Service: service.Id, Latency: 0.5, CreatedAt: day.Add(5 * time.Hour)})
+	service.CreateFailure(&types.Failure{Issue: "maintenance", Maintenance: true, CreatedAt: day.Add(5 * time.Hour)})
+	service.CreateHit(&types.Hit{Service: service.Id, Latency: 0.2, CreatedAt: time.Now()})
+
+	service.Rollup()
+	var hourly []*types.Rollup
+	rollupsDB("hourly_rollups").Where("service = ?", service.Id).Order("start_time asc").Find(&hourly)
+	assert.Len(t, hourly, 2)
+	assert.Equal(t, int64(10), hourly[0].Hits)
+	assert.Equal(t, int64(1), hourly[0].Failures)
+	assert.Equal(t, 0.055, math.Round(hourly[0].AvgLatency*1000)/1000)
+	assert.Equal(t, 0.01, hourly[0].MinLatency)
+	assert.Equal(t, 0.1, hourly[0].MaxLatency)
+	assert.Equal(t, 0.1, hourly[0].P95Latency)
+	assert.Equal(t, int64(1), hourly[1].Failures)
+	assert.Equal(t, int64(0), hourly[1].UptimeFailures)
+	var daily []*types.Rollup
+	rollupsDB("daily_rollups").Where("service = ?", service.Id).Find(&daily)
+	assert.Len(t, daily, 1)
+	assert.Equal(t, int64(11), daily[0].Hits)
+	assert.Equal(t, int64(2), daily[0].Failures)
+
+	// the next rollup will only add new periods
+	service.Rollup()
+	var count int
+	rollupsDB("hourly_rollups").Where("service = ?", service.Id).Count(&count)
+	assert.Equal(t, 2, count)
+
+	// old hits and failures can be deleted, the uptime and graph data are read from the rollups
+	hitsDB().Where("service = ? AND created_at < ?", service.Id, time.Now().Add(-24*time.Hour).UTC().Format(types.TIME)).Delete(&types.Hit{})
+	failuresDB().Where("service = ?", service.Id).Delete(&types.Failure{})
+	hits, err := service.uptimeHitsSince(day.Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(12), hits)
+	failures, err := service.uptimeFailuresSince(day.Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), failures)
+	graph := GraphDataRaw(service, day.Add(-time.Hour), time.Now().Add(time.Minute), "hour", "p95")
+	assert.Len(t, graph.Array, 3)
+	assert.Equal(t, int64(100), graph.Array[0].Value)
+	assert.Equal(t, int64(200), graph.Array[2].Value)
+}
+
+func TestServiceRollupsHead(t *testing.T) {
+	service := createTestService(t, &types.Service{Name: "Rollup Head Service"})
+	day := time.Now().UTC().Add(-10 * 24 * time.Hour).Truncate(24 * time.Hour)
+	service.CreateHit(&types.Hit{Service: service.Id, Latency: 0.1, CreatedAt: day.Add(30 * time.Minute)})
+	service.CreateHit(&types.Hit{Service: service.Id, Latency: 0.1, CreatedAt: day.Add(2*time.Hour + 5*time.Minute)})
+	service.CreateFailure(&types.Failure{Issue: "connection refused", CreatedAt: day.Add(40 * time.Minute)})
+	service.CreateFailure(&types.Failure{Issue: "connection refused", CreatedAt: day.Add(3*time.Hour + 5*time.Minute)})
+	service.Rollup()
+
+	// the rollup of the first hour starts before the time, its hits and failures are counted from the raw rows
+	hits, err := service.uptimeHitsSince(day.Add(15 * time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), hits)
+	failures, err := service.uptimeFailuresSince(day.Add(15 * time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), failures)
+}
+
+func TestServiceRetention(t *testing.T) {
+	assert.Equal(t, 90, retentionDays(0, 90))
+	assert.Equal(t, -1, retentionDays(-1, 90))
+	assert.Equal(t, -1, retentionDays(0, -1))
+	_, ok := retentionCutoff(0)
+	assert.False(t, ok)
+	_, ok = retentionCutoff(-1)
+	assert.False(t, ok)
+
+	service := createTestService(t, &types.Service{
+		Name:              "Retention Service",
+		HitsRetention:     5,
+		FailuresRetention: -1,
+	})
+	old := time.Now().UTC().Add(-10 * 24 * time.Hour)
+	for i := 0; i < 3; i++ {
+		service.CreateHit(&types.Hit{Service: service.Id, Latency: 0.1, CreatedAt: old.Add(time.Duration(i) * time.Minute)})
+	}
+	service.CreateHit(&types.Hit{Service: service.Id, Latency: 0.1, CreatedAt: time.Now().Add(-time.Minute)})
+	service.CreateFailure(&types.Failure{Issue: "connection refused", CreatedAt: old})
+
+	result := CoreApp.Cleanup()
+	assert.True(t, result.Hits >= 3)
+	assert.Equal(t, result, CoreApp.LastCleanup())
+	var count int
+	hitsDB().Where("service = ?", service.Id).Count(&count)
+	assert.Equal(t, 1, count)
+	failuresDB().Where("service = ?", service.Id).Count(&count)
+	assert.Equal(t, 1, count)
+	rollupsDB("hourly_rollups").Where("service = ?", service.Id).Count(&count)
+	assert.NotZero(t, count)
+
+	assert.Equal(t, int64(1), service.deleteBefore("failures", time.Now().UTC()))
+	failuresDB().Where("service = ?", service.Id).Count(&count)
+	assert.Equal(t, 0, count)
+}
+
+func TestServicePause(t *testing.T) {
+	service := createTestService(t, &types.Service{Name: "Paused Service"})
+	assert.False(t, service.Paused)
+	err := service.Pause()
+	assert.Nil(t, err)
+	assert.True(t, service.Paused)
+	var saved types.Service
+	servicesDB().Where("id = ?", service.Id).First(&saved)
+	assert.True(t, saved.Paused)
+	err = service.Resume()
+	assert.Nil(t, err)
+	assert.False(t, service.Paused)
+	servicesDB().Where("id = ?", service.Id).First(&saved)
+	assert.False(t, saved.Paused)
+}
+
+func TestServiceUpdateZeroValues(t *testing.T) {
+	service := createTestService(t, &types.Service{
+		Name:           "Cleared Service",
+		PacketCount:    5,
+		MaxPacketLoss:  50,
+		DnsRecord:      "A",
+		DnsResolver:    "8.8.8.8:53",
+		CertExpiryWarn: 14,
+		Method:         "PUT",
+		Headers:        "X-Token=abc",
+		ContentType:    "application/json",
+		SkipVerify:     true,
+		NoRedirects:    true,
+		GrpcService:    "payments",
+		GrpcTls:        true,
+		Retries:        3,
+		RetryDelay:     1000,
+		GroupName:      "Payments",
+		ParentId:       newServiceId,
+		SloTarget:      99.9,
+		SloLatency:     300,
+	})
+	service.PacketCount = 0
+	service.MaxPacketLoss = 0
+	service.DnsRecord = ""
+	service.DnsResolver = ""
+	service.CertExpiryWarn = 0
+	service.Method = ""
+	service.Headers = ""
+	service.ContentType = ""
+	service.SkipVerify = false
+	service.NoRedirects = false
+	service.GrpcService = ""
+	service.GrpcTls = false
+	service.Retries = 0
+	service.RetryDelay = 0
+	service.GroupName = ""
+	service.ParentId = 0
+	service.SloTarget = 0
+	service.SloLatency = 0
+	assert.Nil(t, service.Update(false))
+	var saved types.Service
+	servicesDB().Where("id = ?", service.Id).First(&saved)
+	assert.Equal(t, 0, saved.PacketCount)
+	assert.Equal(t, 0, saved.MaxPacketLoss)
+	assert.Empty(t, saved.DnsRecord)
+	assert.Empty(t, saved.DnsResolver)
+	assert.Zero(t, saved.CertExpiryWarn)
+	assert.Empty(t, saved.Method)
+	assert.Empty(t, saved.Headers)
+	assert.Empty(t, saved.ContentType)
+	assert.False(t, saved.SkipVerify)
+	assert.False(t, saved.NoRedirects)
+	assert.Empty(t, saved.GrpcService)
+	assert.False(t, saved.GrpcTls)
+	assert.Zero(t, saved.Retries)
+	assert.Zero(t, saved.RetryDelay)
+	assert.Empty(t, saved.GroupName)
+	assert.Zero(t, saved.ParentId)
+	assert.Zero(t, saved.SloTarget)
+	assert.Zero(t, saved.SloLatency)
+}
+
+func TestServiceNormalize(t *testing.T) {
+	service := createTestService(t, &types.Service{
+		Name:      "Normalized Service",
+		FailAfter: 3,
+		Quorum:    2,
+	})
+	assert.Equal(t, 1, service.RecoverAfter)
+	assert.Equal(t, 30, service.SloWindow)
+	service.FailAfter = 0
+	service.RecoverAfter = -1
+	service.Quorum = 0
+	service.SloWindow = 0
+	service.SloBurnRate = -1
+	assert.Nil(t, service.Update(false))
+	var saved types.Service
+	servicesDB().Where("id = ?", service.Id).First(&saved)
+	assert.Equal(t, 1, saved.FailAfter)
+	assert.Equal(t, 1, saved.RecoverAfter)
+	assert.Equal(t, 1, saved.Quorum)
+	assert.Equal(t, 30, saved.SloWindow)
+	assert.Equal(t, 14.4, saved.SloBurnRate)
+}
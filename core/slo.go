@@ -0,0 +1,162 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"github.com/hunterlong/statup/core/notifier"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"math"
+	"sort"
+	"time"
+)
+
+// burnWindow is the period the error budget burn rate is measured over
+const burnWindow = time.Hour
+
+// HasSLO returns true if the service has an availability or latency objective
+func (s *Service) HasSLO() bool {
+	return s.SloTarget > 0 || s.SloLatency > 0
+}
+
+// sloWindow returns the SLO's rolling window in days, defaulting to 30 days
+func (s *Service) sloWindow() int {
+	if s.SloWindow <= 0 {
+		return 30
+	}
+	return s.SloWindow
+}
+
+// burnRateAlert returns the burn rate that will trigger a notification, defaulting to 14.4 which will use
+// 2% of a 30 day budget in one hour
+func (s *Service) burnRateAlert() float64 {
+	if s.SloBurnRate <= 0 {
+		return 14.4
+	}
+	return s.SloBurnRate
+}
+
+// errorRatio returns the percent of checks that failed since a time, not including checks during a maintenance window
+func (s *Service) errorRatio(ago time.Time) (hits uint64, failures uint64, ratio float64) {
+	hits, _ = s.uptimeHitsSince(ago)
+	failures, _ = s.uptimeFailuresSince(ago)
+	if hits+failures == 0 {
+		return hits, failures, 0
+	}
+	return hits, failures, float64(failures) / float64(hits+failures) * 100
+}
+
+// BurnRate returns how fast the service is using its error budget over the last hour, relative to using the
+// whole budget evenly over the SLO window
+func (s *Service) BurnRate() float64 {
+	if s.SloTarget <= 0 || s.SloTarget >= 100 {
+		return 0
+	}
+	_, _, ratio := s.errorRatio(time.Now().Add(-burnWindow))
+	return ratio / (100 - s.SloTarget)
+}
+
+// SLO returns the service's objectives with its uptime, remaining error budget, burn rate and p95 latency over
+// the SLO window, or nil if the service doesn't have an SLO
+func (s *Service) SLO() *types.SLO {
+	if !s.HasSLO() {
+		return nil
+	}
+	ago := time.Now().Add(-time.Duration(s.sloWindow()) * 24 * time.Hour)
+	hits, failures, ratio := s.errorRatio(ago)
+	slo := &types.SLO{
+		Target:        s.SloTarget,
+		Window:        s.sloWindow(),
+		Hits:          hits,
+		Failures:      failures,
+		Uptime:        round(100 - ratio),
+		BurnRateAlert: s.burnRateAlert(),
+		LatencyTarget: s.SloLatency,
+	}
+	if s.SloTarget > 0 && s.SloTarget < 100 {
+		slo.ErrorBudget = round(100 - s.SloTarget)
+		slo.BudgetRemaining = round(100 - ratio/(100-s.SloTarget)*100)
+		slo.BurnRate = round(s.BurnRate())
+	}
+	if s.SloLatency > 0 {
+		slo.LatencyP95 = round(s.latencyPercentile(ago, 95) * 1000)
+		slo.LatencyMet = slo.LatencyP95 <= float64(s.SloLatency)
+	}
+	return slo
+}
+
+// latencyPercentile returns the latency in seconds that p percent of the service's hits since a time were faster than
+func (s *Service) latencyPercentile(ago time.Time, p float64) float64 {
+	var latencies []float64
+	err := hitsDB().Where("service = ? AND created_at > ? AND maintenance = ?", s.Id, ago.UTC().Format("2006-01-02 15:04:05"), false).Pluck("latency", &latencies)
+	if err.Error != nil {
+		utils.Log(2, fmt.Sprintf("Issue getting latencies for service %v: %v", s.Name, err.Error))
+		return 0
+	}
+	return percentile(latencies, p)
+}
+
+// percentile returns the nearest-rank percentile of the values
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	rank := int(math.Ceil(p/100*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(values) {
+		rank = len(values) - 1
+	}
+	return values[rank]
+}
+
+// round returns the value rounded to 2 decimals
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// checkErrorBudget will send a notification once when the service's burn rate reaches its alert threshold, the
+// notification can be sent again after the burn rate has dropped below the threshold
+func (s *Service) checkErrorBudget() {
+	if s.SloTarget <= 0 || s.SloTarget >= 100 {
+		return
+	}
+	rate := s.BurnRate()
+	if rate < s.burnRateAlert() {
+		s.BudgetAlerted = false
+		return
+	}
+	if s.BudgetAlerted {
+		return
+	}
+	s.BudgetAlerted = true
+	utils.Log(2, fmt.Sprintf("Service %v is burning its error budget %0.1fx faster than its SLO of %v%% allows", s.Name, rate, s.SloTarget))
+	notifier.OnBudgetBurn(s.Service, s.SLO())
+}
+
+// LatencyPercentilesText returns the service's p50, p95 and p99 latency over the last 24 hours
+func (s *Service) LatencyPercentilesText() string {
+	var latencies []float64
+	ago := time.Now().Add(-24 * time.Hour).UTC().Format("2006-01-02 15:04:05")
+	hitsDB().Where("service = ? AND created_at > ?", s.Id, ago).Pluck("latency", &latencies)
+	if len(latencies) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("p50 %0.0fms, p95 %0.0fms, p99 %0.0fms over the last 24 hours", percentile(latencies, 50)*1000, percentile(latencies, 95)*1000, percentile(latencies, 99)*1000)
+}
@@ -0,0 +1,210 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"strings"
+	"time"
+)
+
+// tokenPrefix starts every API token so they're easy to recognize
+const tokenPrefix = "statup_"
+
+// tokenUsedInterval is how often a token's last used time is saved, so every request doesn't write to the database
+const tokenUsedInterval = time.Minute
+
+type Token struct {
+	*types.Token
+}
+
+// ReturnToken returns *core.Token based off a *types.Token
+func ReturnToken(t *types.Token) *Token {
+	return &Token{t}
+}
+
+// hashToken returns the SHA256 hash of a token that is saved in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes as a hex string
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newToken returns a new random API token
+func newToken() (string, error) {
+	token, err := randomHex(20)
+	if err != nil {
+		return "", err
+	}
+	return tokenPrefix + token, nil
+}
+
+// inScopes returns true if the scope is in the list of scopes
+func inScopes(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// validScopes returns an error if a comma separated list of scopes is empty or has an unknown scope
+func validScopes(scopes string) error {
+	if strings.TrimSpace(scopes) == "" {
+		return errors.New("token requires at least one scope")
+	}
+	for _, s := range strings.Split(scopes, ",") {
+		if !inScopes(types.TokenScopes, strings.TrimSpace(s)) {
+			return errors.New(fmt.Sprintf("token scope must be one of %v", strings.Join(types.TokenScopes, ", ")))
+		}
+	}
+	return nil
+}
+
+// SelectToken returns the Token based on the token's ID
+func SelectToken(id int64) (*Token, error) {
+	var token Token
+	err := tokensDB().First(&token, id)
+	return &token, err.Error
+}
+
+// Tokens returns the user's API tokens
+func (u *User) Tokens() []*Token {
+	var tokens []*Token
+	err := tokensDB().Where("user_id = ?", u.Id).Order("id desc").Find(&tokens)
+	if err.Error != nil {
+		utils.Log(2, fmt.Sprintf("Issue getting tokens for user %v: %v", u.Username, err.Error))
+	}
+	return tokens
+}
+
+// Create will generate a new token and save its hash into the database. The token is set on
+// the Value field, it can't be retrieved again after it's created.
+func (t *Token) Create() (string, error) {
+	if strings.TrimSpace(t.Name) == "" {
+		return "", errors.New("token requires a name")
+	}
+	var scopes []string
+	for _, s := range strings.Split(t.Scopes, ",") {
+		scopes = append(scopes, strings.TrimSpace(s))
+	}
+	t.Scopes = strings.Join(scopes, ",")
+	if err := validScopes(t.Scopes); err != nil {
+		return "", err
+	}
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	t.Hash = hashToken(token)
+	t.Prefix = token[:len(tokenPrefix)+6]
+	t.CreatedAt = time.Now().UTC()
+	db := tokensDB().Create(t)
+	if db.Error != nil {
+		utils.Log(3, fmt.Sprintf("Failed to create token %v: %v", t.Name, db.Error))
+		return "", db.Error
+	}
+	t.Value = token
+	return token, nil
+}
+
+// Revoke will delete the token so it can no longer be used
+func (t *Token) Revoke() error {
+	return tokensDB().Delete(t.Token).Error
+}
+
+// Expired returns true if the token has an expiry that has passed
+func (t *Token) Expired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt)
+}
+
+// HasScope returns true if the token can be used for the scope
+func (t *Token) HasScope(scope string) bool {
+	return inScopes(t.ScopeList(), scope)
+}
+
+// ScopeList returns the token's scopes
+func (t *Token) ScopeList() []string {
+	return strings.Split(t.Scopes, ",")
+}
+
+// ExpiresText returns a human readable date the token expires on
+func (t *Token) ExpiresText() string {
+	if t.ExpiresAt.IsZero() {
+		return "Never"
+	}
+	return t.ExpiresAt.Format("Jan _2, 2006")
+}
+
+// LastUsedText returns a human readable time the token was last used
+func (t *Token) LastUsedText() string {
+	if t.LastUsed.IsZero() {
+		return "Never"
+	}
+	return utils.Timestamp(t.LastUsed).Ago()
+}
+
+// touch will save the time the token was used, at most once every tokenUsedInterval
+func (t *Token) touch() {
+	now := time.Now().UTC()
+	if now.Sub(t.LastUsed) < tokenUsedInterval {
+		return
+	}
+	t.LastUsed = now
+	err := tokensDB().Where("id = ?", t.Id).UpdateColumn("last_used", now)
+	if err.Error != nil {
+		utils.Log(2, fmt.Sprintf("Issue saving last used time for token %v: %v", t.Name, err.Error))
+	}
+}
+
+// AuthToken returns the Token and its User for an API token, and true if the token exists and hasn't expired
+func AuthToken(token string) (*Token, *User, bool) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, nil, false
+	}
+	var t Token
+	db := tokensDB().Where("hash = ?", hashToken(token)).First(&t)
+	if db.Error != nil || t.Expired() {
+		return nil, nil, false
+	}
+	user, err := SelectUser(t.User)
+	if err != nil {
+		return nil, nil, false
+	}
+	t.touch()
+	return &t, user, true
+}
+
+// TokenScopes returns the scopes a token can have
+func (c *Core) TokenScopes() []string {
+	return types.TokenScopes
+}
@@ -0,0 +1,260 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/hunterlong/statup/utils"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets when they enable two-factor authentication
+	recoveryCodeCount = 10
+	// maxCodeFailures is how many incorrect codes a user can enter within codeFailureWindow before they're locked out
+	maxCodeFailures   = 5
+	codeFailureWindow = 15 * time.Minute
+	// recoveryCodeCost is the bcrypt cost of recovery codes, the lowest cost is used because they're random and
+	// each login has to compare the code with every recovery code the user has left
+	recoveryCodeCost = bcrypt.MinCost
+)
+
+var (
+	ErrInvalidCode     = errors.New("the code is not valid, try again")
+	ErrTooManyCodes    = errors.New("too many incorrect codes, try again later")
+	ErrTwoFactorActive = errors.New("two-factor authentication is already enabled")
+)
+
+// codeState is the last code a user logged in with and the incorrect codes they entered since the first one. It's
+// locked while one of the user's codes is checked, so a code can't be used twice at the same time.
+type codeState struct {
+	sync.Mutex
+	used     string
+	failures int
+	since    time.Time
+}
+
+var (
+	codeStatesLock sync.Mutex
+	codeStates     = make(map[int64]*codeState)
+)
+
+// lockCodes returns the user's locked code state, codes of other users can be checked at the same time
+func lockCodes(id int64) *codeState {
+	codeStatesLock.Lock()
+	state := codeStates[id]
+	if state == nil {
+		state = &codeState{}
+		codeStates[id] = state
+	}
+	codeStatesLock.Unlock()
+	state.Lock()
+	return state
+}
+
+// TwoFactorRequired returns true if the user has to enable two-factor authentication before they can login.
+// Single sign-on logins are only exempt when TrustSsoTwoFactor is set, to leave it to the provider.
+func (c *Core) TwoFactorRequired(u *User, sso bool) bool {
+	if sso && c.TrustSsoTwoFactor {
+		return false
+	}
+	return c.RequireTwoFactor && !u.TotpEnabled
+}
+
+// TwoFactorKey returns the user's TOTP key to scan into an authenticator app. The secret is saved the
+// first time, so the QR code doesn't change until two-factor authentication is enabled with a code from it.
+func (u *User) TwoFactorKey() (*otp.Key, error) {
+	if u.TotpEnabled {
+		return nil, ErrTwoFactorActive
+	}
+	issuer := "Statup"
+	if CoreApp != nil && CoreApp.Name != "" {
+		issuer = CoreApp.Name
+	}
+	opts := totp.GenerateOpts{Issuer: issuer, AccountName: u.Username}
+	if u.TotpSecret != "" {
+		secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(u.TotpSecret)
+		if err != nil {
+			return nil, err
+		}
+		opts.Secret = secret
+	}
+	key, err := totp.Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	if u.TotpSecret == "" {
+		u.TotpSecret = key.Secret()
+		if err := u.saveTwoFactor(); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// EnableTwoFactor will turn on two-factor authentication if the code is from the user's TOTP key, and returns
+// the user's recovery codes. Only a bcrypt hash of each recovery code is saved, so they're only shown once.
+func (u *User) EnableTwoFactor(code string) ([]string, error) {
+	if u.TotpEnabled {
+		return nil, ErrTwoFactorActive
+	}
+	state := lockCodes(u.Id)
+	defer state.Unlock()
+	if err := state.lockout(); err != nil {
+		return nil, err
+	}
+	code = strings.TrimSpace(code)
+	if u.TotpSecret == "" || state.used == code || !totp.Validate(code, u.TotpSecret) {
+		state.failed()
+		return nil, ErrInvalidCode
+	}
+	state.used = code
+	state.failures = 0
+	codes, err := u.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	u.TotpEnabled = true
+	if err := u.saveTwoFactor(); err != nil {
+		return nil, err
+	}
+	utils.Log(1, fmt.Sprintf("User %v enabled two-factor authentication", u.Username))
+	return codes, nil
+}
+
+// DisableTwoFactor will turn off two-factor authentication and remove the user's TOTP secret and recovery codes
+func (u *User) DisableTwoFactor() error {
+	u.TotpEnabled = false
+	u.TotpSecret = ""
+	u.RecoveryCodes = ""
+	return u.saveTwoFactor()
+}
+
+// VerifyTwoFactor returns nil if the code is the current code from the user's authenticator app, or one of their
+// recovery codes, which can only be used once. A code can't be used twice, and users are locked out for a while
+// after too many incorrect codes.
+func (u *User) VerifyTwoFactor(code string) error {
+	if !u.TotpEnabled {
+		return ErrInvalidCode
+	}
+	state := lockCodes(u.Id)
+	defer state.Unlock()
+	if err := state.lockout(); err != nil {
+		return err
+	}
+	code = strings.TrimSpace(code)
+	if state.used != code && totp.Validate(code, u.TotpSecret) {
+		state.used = code
+		state.failures = 0
+		return nil
+	}
+	if u.useRecoveryCode(code) {
+		state.failures = 0
+		return nil
+	}
+	state.failed()
+	return ErrInvalidCode
+}
+
+// lockout returns ErrTooManyCodes if the user entered too many incorrect codes within codeFailureWindow
+func (c *codeState) lockout() error {
+	if time.Since(c.since) > codeFailureWindow {
+		c.failures = 0
+	}
+	if c.failures >= maxCodeFailures {
+		return ErrTooManyCodes
+	}
+	return nil
+}
+
+// failed will count an incorrect code, the window starts at the first incorrect code
+func (c *codeState) failed() {
+	if c.failures == 0 {
+		c.since = time.Now()
+	}
+	c.failures++
+}
+
+// RecoveryCodesLeft returns how many recovery codes the user hasn't used
+func (u *User) RecoveryCodesLeft() int {
+	if u.RecoveryCodes == "" {
+		return 0
+	}
+	return len(strings.Split(u.RecoveryCodes, ","))
+}
+
+// newRecoveryCodes sets new recovery codes for the user and returns them, formatted like 'a1b2c-3d4e5'
+func (u *User) newRecoveryCodes() ([]string, error) {
+	var codes, hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomHex(5)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), recoveryCodeCost)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, string(hash))
+	}
+	u.RecoveryCodes = strings.Join(hashes, ",")
+	return codes, nil
+}
+
+// useRecoveryCode returns true if the code is one of the user's recovery codes, and removes it
+func (u *User) useRecoveryCode(code string) bool {
+	code = strings.ToLower(strings.Replace(code, "-", "", -1))
+	// recovery codes are 10 characters, other codes don't have to be compared with every hash
+	if len(code) != 10 {
+		return false
+	}
+	var left []string
+	found := false
+	for _, h := range strings.Split(u.RecoveryCodes, ",") {
+		if !found && CheckHash(code, h) {
+			found = true
+			continue
+		}
+		left = append(left, h)
+	}
+	if !found || u.RecoveryCodes == "" {
+		return false
+	}
+	u.RecoveryCodes = strings.Join(left, ",")
+	if err := u.saveTwoFactor(); err != nil {
+		utils.Log(3, fmt.Sprintf("Issue removing used recovery code for user %v: %v", u.Username, err))
+		return false
+	}
+	utils.Log(1, fmt.Sprintf("User %v logged in with a recovery code, %v are left", u.Username, u.RecoveryCodesLeft()))
+	return true
+}
+
+// saveTwoFactor will save the user's two-factor authentication columns, which can be cleared
+func (u *User) saveTwoFactor() error {
+	return usersDB().Where("id = ?", u.Id).UpdateColumns(map[string]interface{}{
+		"totp_secret":    u.TotpSecret,
+		"totp_enabled":   u.TotpEnabled,
+		"recovery_codes": u.RecoveryCodes,
+	}).Error
+}
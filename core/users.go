@@ -16,6 +16,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
@@ -47,13 +48,15 @@ func SelectUsername(username string) (*User, error) {
 	return &user, err.Error
 }
 
-// Delete will remove the user record from the database
+// Delete will remove the user record and the user's API tokens from the database
 func (u *User) Delete() error {
+	tokensDB().Where("user_id = ?", u.Id).Delete(&types.Token{})
 	return usersDB().Delete(u).Error
 }
 
 // Update will update the user's record in database
 func (u *User) Update() error {
+	u.setRole()
 	u.Password = utils.HashPassword(u.Password)
 	u.ApiKey = utils.NewSHA1Hash(5)
 	u.ApiSecret = utils.NewSHA1Hash(10)
@@ -63,6 +66,10 @@ func (u *User) Update() error {
 // Create will insert a new user into the database
 func (u *User) Create() (int64, error) {
 	u.CreatedAt = time.Now()
+	if u.AuthSource == "" {
+		u.AuthSource = types.SourceLocal
+	}
+	u.setRole()
 	u.Password = utils.HashPassword(u.Password)
 	u.ApiKey = utils.NewSHA1Hash(5)
 	u.ApiSecret = utils.NewSHA1Hash(10)
@@ -101,8 +108,132 @@ func AuthUser(username, password string) (*User, bool) {
 	return nil, false
 }
 
+// ErrUserExists is returned when a single sign-on or LDAP login has the username of an existing user. Existing
+// users are never used for a login from another source, a local user has to link their single sign-on account first.
+var ErrUserExists = errors.New("a user with this username already exists")
+
+// selectSubject returns the user that was created for the subject of the source, or the local user that linked
+// their account to the subject
+func selectSubject(source, subject string) (*User, error) {
+	var user User
+	err := usersDB().Where("auth_subject = ? AND auth_source IN (?)", subject, []string{source, types.SourceLocal}).First(&user)
+	return &user, err.Error
+}
+
+// provisionUser returns the User that logged in with single sign-on or LDAP, creating them on their first login
+// with an unusable random password. Users are found by the source and its subject for the user, the username is
+// only used for new users. The role is saved on every login when syncRole is true, otherwise it's only used for
+// new users. Local users that linked their account keep the role they have in Statup.
+func provisionUser(source, subject, username, email, role string, syncRole bool) (*User, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("%v login does not have a subject", source)
+	}
+	user, err := selectSubject(source, subject)
+	if err != nil {
+		if _, err := SelectUsername(username); err == nil {
+			return nil, ErrUserExists
+		}
+		password, err := randomHex(32)
+		if err != nil {
+			return nil, err
+		}
+		if email == "" {
+			email = username
+		}
+		user = ReturnUser(&types.User{
+			Username:    username,
+			Password:    password,
+			Email:       email,
+			Role:        role,
+			AuthSource:  source,
+			AuthSubject: subject,
+		})
+		if _, err := user.Create(); err != nil {
+			return nil, err
+		}
+		utils.Log(1, fmt.Sprintf("Created %v user %v from %v", user.Role, user.Username, source))
+		return user, nil
+	}
+	if !syncRole || user.RoleName() == role || user.SourceName() == types.SourceLocal {
+		return user, nil
+	}
+	user.Role = role
+	user.setRole()
+	db := usersDB().Where("id = ?", user.Id).UpdateColumns(map[string]interface{}{
+		"role":          user.Role,
+		"administrator": user.Admin,
+	})
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	utils.Log(1, fmt.Sprintf("User %v is now %v from their %v groups", user.Username, user.Role, source))
+	return user, nil
+}
+
+// LinkSubject will link a local user to their single sign-on subject, so they can login with single sign-on
+func (u *User) LinkSubject(subject string) error {
+	if u.SourceName() != types.SourceLocal {
+		return errors.New("only local users can link a single sign-on account")
+	}
+	if subject == "" {
+		return errors.New("single sign-on login does not have a subject")
+	}
+	if other, err := selectSubject(types.SourceOIDC, subject); err == nil && other.Id != u.Id {
+		return errors.New("this single sign-on account is already used by another user")
+	}
+	u.AuthSource = types.SourceLocal
+	u.AuthSubject = subject
+	return usersDB().Where("id = ?", u.Id).UpdateColumns(map[string]interface{}{
+		"auth_source":  u.AuthSource,
+		"auth_subject": u.AuthSubject,
+	}).Error
+}
+
+// SourceName returns where the user was created, users saved before sources were added are local users
+func (u *User) SourceName() string {
+	if u.AuthSource == "" {
+		return types.SourceLocal
+	}
+	return u.AuthSource
+}
+
 // CheckHash returns true if the password matches with a hashed bcrypt password
 func CheckHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// roleLevels orders the user roles, a role can do everything the roles with a lower level can
+var roleLevels = map[string]int{
+	types.RoleViewer:   1,
+	types.RoleOperator: 2,
+	types.RoleAdmin:    3,
+}
+
+// RoleName returns the user's role. Users saved before roles were added are admins if they have
+// the Admin flag, otherwise operators.
+func (u *User) RoleName() string {
+	if _, ok := roleLevels[u.Role]; ok {
+		return u.Role
+	}
+	if u.Admin {
+		return types.RoleAdmin
+	}
+	return types.RoleOperator
+}
+
+// HasRole returns true if the user has the role, or a role with more access
+func (u *User) HasRole(role string) bool {
+	return roleLevels[u.RoleName()] >= roleLevels[role]
+}
+
+// setRole will save the user's role name and keep the Admin flag in sync with it
+func (u *User) setRole() {
+	u.Role = u.RoleName()
+	u.Admin = u.Role == types.RoleAdmin
+}
+
+// UserRoles returns the roles a user can have
+func (c *Core) UserRoles() []string {
+	return types.UserRoles
+}
@@ -16,9 +16,21 @@
 package core
 
 import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"github.com/hunterlong/statup/types"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreateUser(t *testing.T) {
@@ -105,6 +117,433 @@ func TestCheckPassword(t *testing.T) {
 	assert.True(t, pass)
 }
 
+func TestUserRoles(t *testing.T) {
+	legacy := ReturnUser(&types.User{Username: "legacy"})
+	assert.Equal(t, types.RoleOperator, legacy.RoleName())
+	assert.True(t, legacy.HasRole(types.RoleViewer))
+	assert.True(t, legacy.HasRole(types.RoleOperator))
+	assert.False(t, legacy.HasRole(types.RoleAdmin))
+	legacy.Admin = true
+	assert.Equal(t, types.RoleAdmin, legacy.RoleName())
+
+	user := ReturnUser(&types.User{
+		Username: "viewer",
+		Password: "password123",
+		Email:    "viewer@email.com",
+		Admin:    true,
+		Role:     types.RoleViewer,
+	})
+	_, err := user.Create()
+	assert.Nil(t, err)
+	assert.False(t, user.Admin)
+	user, err = SelectUsername("viewer")
+	assert.Nil(t, err)
+	assert.Equal(t, types.RoleViewer, user.Role)
+	assert.False(t, user.HasRole(types.RoleOperator))
+	user.Role = types.RoleAdmin
+	err = user.Update()
+	assert.Nil(t, err)
+	assert.True(t, user.Admin)
+	assert.Nil(t, user.Delete())
+}
+
+func TestUserTokens(t *testing.T) {
+	user, err := SelectUser(1)
+	assert.Nil(t, err)
+	token := ReturnToken(&types.Token{User: user.Id, Name: "Deploy", Scopes: "read, services:write"})
+	value, err := token.Create()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(value, tokenPrefix))
+	assert.Equal(t, value, token.Value)
+	assert.NotEqual(t, value, token.Hash)
+	assert.Equal(t, "read,services:write", token.Scopes)
+	assert.Len(t, user.Tokens(), 1)
+
+	found, owner, ok := AuthToken(value)
+	assert.True(t, ok)
+	assert.Equal(t, user.Id, owner.Id)
+	assert.True(t, found.HasScope(types.ScopeServicesWrite))
+	assert.False(t, found.HasScope(types.ScopeMetrics))
+	assert.False(t, found.LastUsed.IsZero())
+	_, _, ok = AuthToken(value + "0")
+	assert.False(t, ok)
+
+	_, err = ReturnToken(&types.Token{User: user.Id, Name: "Admin", Scopes: "admin"}).Create()
+	assert.NotNil(t, err)
+	expired := ReturnToken(&types.Token{User: user.Id, Name: "Old", Scopes: "read", ExpiresAt: time.Now().Add(-time.Hour)})
+	old, err := expired.Create()
+	assert.Nil(t, err)
+	_, _, ok = AuthToken(old)
+	assert.False(t, ok)
+
+	assert.Nil(t, token.Revoke())
+	assert.Nil(t, expired.Revoke())
+	_, _, ok = AuthToken(value)
+	assert.False(t, ok)
+	assert.Len(t, user.Tokens(), 0)
+}
+
+// mockOIDCProvider is an OpenID Connect provider that returns an ID token with the claims
+// from the claims function, signed by the key
+func mockOIDCProvider(key *rsa.PrivateKey, claims func() map[string]interface{}) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "statup" || secret != "secret" || r.FormValue("code") != "code" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test"})
+		payload, _ := json.Marshal(claims())
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		hash := sha256.Sum256([]byte(signed))
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": signed + "." + base64.RawURLEncoding.EncodeToString(signature),
+		})
+	})
+	return server
+}
+
+func TestOIDCLogin(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	var claims map[string]interface{}
+	server := mockOIDCProvider(key, func() map[string]interface{} { return claims })
+	defer server.Close()
+	defer func() {
+		CoreApp.OidcIssuer = ""
+		CoreApp.OidcClientId = ""
+		CoreApp.OidcClientSecret = ""
+		CoreApp.OidcAdminGroups = ""
+		CoreApp.OidcOperatorGroups = ""
+		CoreApp.DisablePasswords = false
+	}()
+
+	CoreApp.DisablePasswords = true
+	assert.False(t, CoreApp.OIDCEnabled())
+	assert.False(t, CoreApp.PasswordLoginDisabled())
+	CoreApp.OidcIssuer = server.URL
+	CoreApp.OidcClientId = "statup"
+	CoreApp.OidcClientSecret = "secret"
+	CoreApp.OidcAdminGroups = "admins"
+	CoreApp.OidcOperatorGroups = "oncall, sre"
+	assert.True(t, CoreApp.OIDCEnabled())
+	assert.True(t, CoreApp.PasswordLoginDisabled())
+
+	redirect := "http://localhost/oauth/callback"
+	authURL, state, nonce, err := CoreApp.OIDCAuthURL(redirect)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(authURL, server.URL+"/authorize?"))
+	assert.Contains(t, authURL, "client_id=statup")
+	assert.Contains(t, authURL, "nonce="+nonce)
+	assert.Contains(t, authURL, "state="+state)
+	assert.NotEqual(t, state, nonce)
+
+	claims = map[string]interface{}{
+		"iss":                server.URL,
+		"aud":                "statup",
+		"sub":                "1234",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              nonce,
+		"preferred_username": "ssouser",
+		"email":              "ssouser@email.com",
+		"email_verified":     true,
+		"groups":             []string{"users", "sre"},
+	}
+	login, err := CoreApp.OIDCExchange("code", redirect, nonce)
+	assert.Nil(t, err)
+	assert.Equal(t, "ssouser", login.Username)
+	assert.Equal(t, []string{"users", "sre"}, login.Groups)
+	user, err := CoreApp.OIDCUser(login)
+	assert.Nil(t, err)
+	assert.NotZero(t, user.Id)
+	assert.Equal(t, types.RoleOperator, user.Role)
+	assert.Equal(t, "ssouser@email.com", user.Email)
+	assert.Equal(t, types.SourceOIDC, user.AuthSource)
+
+	claims["groups"] = []string{"admins"}
+	login, err = CoreApp.OIDCExchange("code", redirect, nonce)
+	assert.Nil(t, err)
+	existing, err := CoreApp.OIDCUser(login)
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, existing.Id)
+	existing, err = SelectUser(user.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, types.RoleAdmin, existing.Role)
+	assert.True(t, existing.Admin)
+
+	_, err = CoreApp.OIDCExchange("wrong", redirect, nonce)
+	assert.NotNil(t, err)
+	_, err = CoreApp.OIDCExchange("code", redirect, "othernonce")
+	assert.NotNil(t, err)
+	claims["aud"] = "otherclient"
+	_, err = CoreApp.OIDCExchange("code", redirect, nonce)
+	assert.NotNil(t, err)
+	claims["aud"] = []string{"otherclient", "statup"}
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	_, err = CoreApp.OIDCExchange("code", redirect, nonce)
+	assert.NotNil(t, err)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	forged := mockOIDCProvider(other, func() map[string]interface{} { return claims })
+	defer forged.Close()
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	provider, err := discoverOIDC(server.URL)
+	assert.Nil(t, err)
+	provider.TokenURL = forged.URL + "/token"
+	_, err = CoreApp.OIDCExchange("code", redirect, nonce)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, existing.Delete())
+}
+
+func TestOIDCKeyCache(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	var requests int
+	keys := map[string]*rsa.PrivateKey{"first": first}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var jwks []map[string]string
+		for kid, key := range keys {
+			jwks = append(jwks, map[string]string{
+				"kid": kid,
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": jwks})
+	}))
+	defer server.Close()
+	provider := &oidcProvider{JwksURL: server.URL}
+
+	key, err := oidcPublicKey(provider, "first")
+	assert.Nil(t, err)
+	assert.Equal(t, first.N, key.N)
+	key, err = oidcPublicKey(provider, "")
+	assert.Nil(t, err)
+	assert.Equal(t, first.N, key.N)
+	assert.Equal(t, 1, requests)
+
+	keys["second"] = second
+	_, err = oidcPublicKey(provider, "second")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, requests)
+	provider.keysFetched = time.Now().Add(-oidcKeyRefresh - time.Second)
+	key, err = oidcPublicKey(provider, "second")
+	assert.Nil(t, err)
+	assert.Equal(t, second.N, key.N)
+	assert.Equal(t, 2, requests)
+	_, err = oidcPublicKey(provider, "")
+	assert.NotNil(t, err)
+	key, err = oidcPublicKey(provider, "first")
+	assert.Nil(t, err)
+	assert.Equal(t, first.N, key.N)
+	assert.Equal(t, 2, requests)
+}
+
+func TestOIDCUserSubjects(t *testing.T) {
+	local := ReturnUser(&types.User{
+		Username: "ssolocal",
+		Password: "password123",
+		Email:    "ssolocal@email.com",
+	})
+	_, err := local.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, types.SourceLocal, local.AuthSource)
+
+	claims := CoreApp.oidcClaims(map[string]interface{}{
+		"iss":                "https://sso.example.com",
+		"sub":                "5678",
+		"preferred_username": "ssolocal",
+		"email":              "ssolocal@email.com",
+		"email_verified":     false,
+	})
+	assert.Equal(t, "https://sso.example.com#5678", claims.ID())
+	assert.Empty(t, claims.Email)
+	_, err = CoreApp.OIDCUser(claims)
+	assert.Equal(t, ErrUserExists, err)
+
+	assert.Nil(t, CoreApp.LinkOIDC(local, claims))
+	user, err := CoreApp.OIDCUser(claims)
+	assert.Nil(t, err)
+	assert.Equal(t, local.Id, user.Id)
+
+	other := *claims
+	other.Issuer = "https://other.example.com"
+	other.Username = "ssolocal2"
+	user, err = CoreApp.OIDCUser(&other)
+	assert.Nil(t, err)
+	assert.NotEqual(t, local.Id, user.Id)
+	assert.Equal(t, types.SourceOIDC, user.AuthSource)
+	assert.NotNil(t, CoreApp.LinkOIDC(local, &other))
+	assert.NotNil(t, user.LinkSubject(claims.ID()))
+
+	assert.Nil(t, user.Delete())
+	assert.Nil(t, local.Delete())
+}
+
+func TestLDAPLogin(t *testing.T) {
+	cert, pool := testCertificate(t)
+	ldapRootCAs = pool
+	address, stop := mockLDAPServer(t, cert)
+	defer stop()
+	defer func() {
+		ldapRootCAs = nil
+		CoreApp.LdapHost = ""
+		CoreApp.LdapStartTLS = false
+		CoreApp.LdapBindDn = ""
+		CoreApp.LdapBindPassword = ""
+		CoreApp.LdapBaseDn = ""
+		CoreApp.LdapAdminGroup = ""
+	}()
+
+	assert.False(t, CoreApp.LDAPEnabled())
+	CoreApp.LdapHost = "ldap://" + address
+	CoreApp.LdapStartTLS = true
+	CoreApp.LdapBindDn = "cn=statup,dc=example,dc=com"
+	CoreApp.LdapBindPassword = "service"
+	CoreApp.LdapBaseDn = "ou=people,dc=example,dc=com"
+	CoreApp.LdapAdminGroup = "cn=admins,ou=groups,dc=example,dc=com"
+	assert.True(t, CoreApp.LDAPEnabled())
+
+	_, ok := CoreApp.AuthLDAP("ldapuser", "wrongpassword")
+	assert.False(t, ok)
+	_, ok = CoreApp.AuthLDAP("ldapuser", "")
+	assert.False(t, ok)
+	_, ok = CoreApp.AuthLDAP("*", "secret")
+	assert.False(t, ok)
+
+	user, ok := CoreApp.AuthLDAP("ldapuser", "secret")
+	assert.True(t, ok)
+	assert.Equal(t, "ldapuser@example.com", user.Email)
+	assert.Equal(t, types.RoleAdmin, user.Role)
+	assert.Equal(t, types.SourceLDAP, user.AuthSource)
+	assert.Equal(t, "uid=ldapuser,ou=people,dc=example,dc=com", user.AuthSubject)
+	again, ok := CoreApp.AuthLDAP("ldapuser", "secret")
+	assert.True(t, ok)
+	assert.Equal(t, user.Id, again.Id)
+	_, ok = AuthUser("ldapuser", "secret")
+	assert.False(t, ok)
+
+	local := ReturnUser(&types.User{
+		Username: "ldaplocal",
+		Password: "password123",
+		Email:    "ldaplocal@email.com",
+	})
+	_, err := local.Create()
+	assert.Nil(t, err)
+	_, ok = CoreApp.AuthLDAP("ldaplocal", "secret")
+	assert.False(t, ok)
+	_, ok = AuthUser("ldaplocal", "password123")
+	assert.True(t, ok)
+
+	CoreApp.LdapBindPassword = "wrongpassword"
+	_, ok = CoreApp.AuthLDAP("ldapuser", "secret")
+	assert.False(t, ok)
+
+	assert.Nil(t, user.Delete())
+	assert.Nil(t, local.Delete())
+}
+
+func TestTwoFactor(t *testing.T) {
+	user := ReturnUser(&types.User{
+		Username: "twofactor",
+		Password: "password123",
+		Email:    "twofactor@email.com",
+	})
+	userId, err := user.Create()
+	assert.Nil(t, err)
+	user, err = SelectUser(userId)
+	assert.Nil(t, err)
+	assert.False(t, CoreApp.TwoFactorRequired(user, false))
+	CoreApp.RequireTwoFactor = true
+	assert.True(t, CoreApp.TwoFactorRequired(user, false))
+	assert.True(t, CoreApp.TwoFactorRequired(user, true))
+	CoreApp.TrustSsoTwoFactor = true
+	assert.True(t, CoreApp.TwoFactorRequired(user, false))
+	assert.False(t, CoreApp.TwoFactorRequired(user, true))
+	CoreApp.TrustSsoTwoFactor = false
+	CoreApp.RequireTwoFactor = false
+
+	key, err := user.TwoFactorKey()
+	assert.Nil(t, err)
+	again, err := user.TwoFactorKey()
+	assert.Nil(t, err)
+	assert.Equal(t, key.Secret(), again.Secret())
+	_, err = user.EnableTwoFactor("000000")
+	assert.Equal(t, ErrInvalidCode, err)
+
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	assert.Nil(t, err)
+	for i := 1; i < maxCodeFailures; i++ {
+		_, err = user.EnableTwoFactor("000000")
+		assert.Equal(t, ErrInvalidCode, err)
+	}
+	_, err = user.EnableTwoFactor(code)
+	assert.Equal(t, ErrTooManyCodes, err)
+	codeStates[user.Id].since = time.Now().Add(-codeFailureWindow - time.Minute)
+	codes, err := user.EnableTwoFactor(code)
+	assert.Nil(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.True(t, user.TotpEnabled)
+	_, err = user.TwoFactorKey()
+	assert.Equal(t, ErrTwoFactorActive, err)
+
+	user, err = SelectUser(userId)
+	assert.Nil(t, err)
+	assert.True(t, user.TotpEnabled)
+	assert.Equal(t, recoveryCodeCount, user.RecoveryCodesLeft())
+	assert.NotContains(t, user.RecoveryCodes, strings.Replace(codes[0], "-", "", -1))
+	assert.True(t, strings.HasPrefix(user.RecoveryCodes, "$2a$"))
+
+	// the code that enabled two-factor authentication can't be used again to login
+	assert.Equal(t, ErrInvalidCode, user.VerifyTwoFactor(code))
+	assert.Nil(t, user.VerifyTwoFactor(strings.ToUpper(codes[0])))
+	assert.Equal(t, ErrInvalidCode, user.VerifyTwoFactor(codes[0]))
+	assert.Equal(t, recoveryCodeCount-1, user.RecoveryCodesLeft())
+
+	for i := 1; i < maxCodeFailures; i++ {
+		assert.Equal(t, ErrInvalidCode, user.VerifyTwoFactor("000000"))
+	}
+	assert.Equal(t, ErrTooManyCodes, user.VerifyTwoFactor(codes[1]))
+	codeStates[user.Id].since = time.Now().Add(-codeFailureWindow - time.Minute)
+	assert.Nil(t, user.VerifyTwoFactor(codes[1]))
+
+	assert.Nil(t, user.DisableTwoFactor())
+	user, err = SelectUser(userId)
+	assert.Nil(t, err)
+	assert.False(t, user.TotpEnabled)
+	assert.Empty(t, user.TotpSecret)
+	assert.Equal(t, 0, user.RecoveryCodesLeft())
+	assert.Nil(t, user.Delete())
+}
+
 func TestDeleteUser(t *testing.T) {
 	user, err := SelectUser(2)
 	assert.Nil(t, err)
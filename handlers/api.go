@@ -21,8 +21,10 @@ import (
 	"github.com/hunterlong/statup/core"
 	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
+	"io"
+	"io/ioutil"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 )
 
@@ -44,7 +46,7 @@ func apiIndexHandler(w http.ResponseWriter, r *http.Request) {
 	for _, s := range out.Services {
 		service := s.Select()
 		service.Failures = nil
-		services = append(services, core.ReturnService(service))
+		services = append(services, apiService(r, service))
 	}
 	out.Services = services
 	w.Header().Set("Content-Type", "application/json")
@@ -52,7 +54,7 @@ func apiIndexHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiRenewHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIRole(r, types.RoleAdmin) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -67,13 +69,41 @@ func apiRenewHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiCheckinHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	vars := mux.Vars(r)
+	checkin := core.FindCheckin(vars["api"])
+	if checkin == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
+	checkin.Receivehit()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(checkin)
+}
+
+func apiCheckinStartHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	checkin := core.FindCheckin(vars["api"])
-	//checkin.Receivehit()
+	if checkin == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	checkin.ReceiveStart()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(checkin)
+}
+
+func apiCheckinFailHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	checkin := core.FindCheckin(vars["api"])
+	if checkin == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(r.Body, 1024))
+	checkin.ReceiveFailure(strings.TrimSpace(string(message)))
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(checkin)
 }
@@ -89,7 +119,8 @@ func apiServiceDataHandler(w http.ResponseWriter, r *http.Request) {
 	grouping := fields.Get("group")
 	startField := utils.StringInt(fields.Get("start"))
 	endField := utils.StringInt(fields.Get("end"))
-	obj := core.GraphDataRaw(service, time.Unix(startField, 0), time.Unix(endField, 0), grouping)
+	stat := fields.Get("stat")
+	obj := core.GraphDataRaw(service, time.Unix(startField, 0), time.Unix(endField, 0), grouping, stat)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(obj)
@@ -108,14 +139,49 @@ func apiServiceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(service)
+	json.NewEncoder(w).Encode(apiService(r, service.Select()))
 }
 
-func apiCreateServiceHandler(w http.ResponseWriter, r *http.Request) {
+func apiServiceSLOHandler(w http.ResponseWriter, r *http.Request) {
 	if !isAPIAuthorized(r) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
+	vars := mux.Vars(r)
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service == nil || !service.HasSLO() {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(service.SLO())
+}
+
+func apiServiceOutagesHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIAuthorized(r) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	limit := int(utils.StringInt(parseGet(r).Get("limit")))
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+	outages := service.Outages(limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(outages)
+}
+
+func apiCreateServiceHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIScope(r, types.RoleAdmin, types.ScopeServicesWrite) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
 	var service *types.Service
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&service)
@@ -124,6 +190,10 @@ func apiCreateServiceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	newService := core.ReturnService(service)
+	if !newService.ValidParent(service.ParentId) {
+		http.Error(w, "service depends on a service that does not exist", http.StatusBadRequest)
+		return
+	}
 	_, err = newService.Create(true)
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -134,7 +204,7 @@ func apiCreateServiceHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiServiceUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIScope(r, types.RoleAdmin, types.ScopeServicesWrite) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -144,11 +214,16 @@ func apiServiceUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
-	var updatedService *types.Service
+	// settings that are not in the request keep their current value
+	updatedService := *service.Service
 	decoder := json.NewDecoder(r.Body)
 	decoder.Decode(&updatedService)
 	updatedService.Id = service.Id
-	service = core.ReturnService(updatedService)
+	service = core.ReturnService(&updatedService)
+	if !service.ValidParent(service.ParentId) {
+		http.Error(w, "service cannot depend on itself or a service that depends on it", http.StatusBadRequest)
+		return
+	}
 	err := service.Update(true)
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -159,8 +234,33 @@ func apiServiceUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(service)
 }
 
+// apiServiceProbeHandler will save a service check result sent from a remote probe
+func apiServiceProbeHandler(w http.ResponseWriter, r *http.Request) {
+	// probes can send their results with a token that can't change the services
+	if !isAPIScope(r, types.RoleAdmin, types.ScopeServicesWrite) && !hasTokenScope(r, types.RoleOperator, types.ScopeProbesWrite) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	var result *types.ProbeResult
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&result)
+	if err != nil || result.Location == "" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	service.RecordProbe(result)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiService(r, service.Select()))
+}
+
 func apiServiceDeleteHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIScope(r, types.RoleAdmin, types.ScopeServicesWrite) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -185,6 +285,46 @@ func apiServiceDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(output)
 }
 
+func apiServicePauseHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIScope(r, types.RoleOperator, types.ScopeServicesWrite) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	err := service.Pause()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiService(r, service.Select()))
+}
+
+func apiServiceResumeHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIScope(r, types.RoleOperator, types.ScopeServicesWrite) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	err := service.Resume()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiService(r, service.Select()))
+}
+
 func apiAllServicesHandler(w http.ResponseWriter, r *http.Request) {
 	if !isAPIAuthorized(r) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
@@ -195,14 +335,14 @@ func apiAllServicesHandler(w http.ResponseWriter, r *http.Request) {
 	for _, s := range allServices {
 		service := s.Select()
 		service.Failures = nil
-		services = append(services, core.ReturnService(service))
+		services = append(services, apiService(r, service))
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(services)
 }
 
 func apiUserHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIRole(r, types.RoleAdmin) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -217,7 +357,7 @@ func apiUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiUserUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIRole(r, types.RoleAdmin) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -242,7 +382,7 @@ func apiUserUpdateHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiUserDeleteHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIRole(r, types.RoleAdmin) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -268,7 +408,7 @@ func apiUserDeleteHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiAllUsersHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIRole(r, types.RoleAdmin) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -278,7 +418,7 @@ func apiAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func apiCreateUsersHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAPIAuthorized(r) {
+	if !isAPIRole(r, types.RoleAdmin) {
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		return
 	}
@@ -305,15 +445,37 @@ func apiCreateUsersHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(output)
 }
 
+// apiService returns the service for an API response. Headers and post data can hold credentials, they are only
+// included for admins, services:write tokens and probes, which need them to check the service.
+func apiService(r *http.Request, service *types.Service) *core.Service {
+	if isAPIScope(r, types.RoleAdmin, types.ScopeServicesWrite) || hasTokenScope(r, types.RoleOperator, types.ScopeProbesWrite) {
+		return core.ReturnService(service)
+	}
+	redacted := *service
+	redacted.Headers = ""
+	redacted.PostData = ""
+	return core.ReturnService(&redacted)
+}
+
+// isAPIAuthorized returns true if the API request is authorized to view services, incidents and maintenance windows
 func isAPIAuthorized(r *http.Request) bool {
-	if os.Getenv("GO_ENV") == "test" {
-		return true
-	}
-	if IsAuthenticated(r) {
+	return isAPIScope(r, types.RoleViewer, types.ScopeRead)
+}
+
+// isAPIRole returns true if the API request is from a logged in user with the role, or a role with more access.
+// Requests using the API secret have every role.
+func isAPIRole(r *http.Request, role string) bool {
+	if isAuthorized(r) {
 		return true
 	}
-	if isAuthorized(r) {
+	return hasRole(r, role)
+}
+
+// isAPIScope returns true if the API request is authorized by isAPIRole, or uses an API token with the scope
+// that belongs to a user with the role
+func isAPIScope(r *http.Request, role, scope string) bool {
+	if isAPIRole(r, role) {
 		return true
 	}
-	return false
+	return hasTokenScope(r, role, scope)
 }
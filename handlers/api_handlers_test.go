@@ -17,6 +17,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/hunterlong/statup/core"
 	"github.com/hunterlong/statup/source"
 	"github.com/hunterlong/statup/types"
@@ -251,6 +252,242 @@ func TestApiServiceDataHandler(t *testing.T) {
 	}
 }
 
+func TestApiServiceProbeHandler(t *testing.T) {
+	rr, err := httpRequestAPI(t, "POST", "/api/services/2/probe", strings.NewReader(`{"location": "us-east", "online": false, "issue": "probe failure"}`))
+	assert.Nil(t, err)
+	body := rr.Body.String()
+	var obj types.Service
+	formatJSON(body, &obj)
+	assert.Equal(t, 200, rr.Code)
+	assert.False(t, obj.Locations["us-east"].Online)
+}
+
+func TestApiServiceProbeScope(t *testing.T) {
+	rr, err := httpRequestAPI(t, "POST", "/api/users/2/tokens", strings.NewReader(`{"name": "Probe", "scopes": "read,probes:write"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var token types.Token
+	formatJSON(rr.Body.String(), &token)
+	req, err := http.NewRequest("POST", "/api/services/2/probe", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer "+token.Value)
+	assert.True(t, hasTokenScope(req, types.RoleOperator, types.ScopeProbesWrite))
+	assert.False(t, hasTokenScope(req, types.RoleAdmin, types.ScopeServicesWrite))
+	rr, err = httpRequestAPI(t, "DELETE", fmt.Sprintf("/api/users/2/tokens/%v", token.Id), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+}
+
+func TestApiServiceRedactsSecrets(t *testing.T) {
+	service := core.SelectService(2)
+	service.Headers = "Authorization=Bearer secret"
+	service.PostData = "password=secret"
+	defer func() {
+		service.Headers = ""
+		service.PostData = ""
+	}()
+	rr, err := httpRequestAPI(t, "POST", "/api/users/2/tokens", strings.NewReader(`{"name": "Dashboard", "scopes": "read"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var token types.Token
+	formatJSON(rr.Body.String(), &token)
+
+	req, err := http.NewRequest("GET", "/api/services/2", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer "+token.Value)
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	var obj types.Service
+	formatJSON(rr.Body.String(), &obj)
+	assert.Equal(t, service.Name, obj.Name)
+	assert.Empty(t, obj.Headers)
+	assert.Empty(t, obj.PostData)
+
+	rr, err = httpRequestAPI(t, "GET", "/api/services/2", nil)
+	assert.Nil(t, err)
+	formatJSON(rr.Body.String(), &obj)
+	assert.Equal(t, "Authorization=Bearer secret", obj.Headers)
+	assert.Equal(t, "password=secret", obj.PostData)
+
+	rr, err = httpRequestAPI(t, "DELETE", fmt.Sprintf("/api/users/2/tokens/%v", token.Id), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+}
+
+func TestApiServiceProbeHandlerNoLocation(t *testing.T) {
+	rr, err := httpRequestAPI(t, "POST", "/api/services/2/probe", strings.NewReader(`{"online": true}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, rr.Code)
+}
+
+func TestApiCheckinHandlerNotFound(t *testing.T) {
+	rr, err := httpRequestAPI(t, "GET", "/api/checkin/missingcheckin", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 404, rr.Code)
+}
+
+func TestApiCheckinFailHandlerNotFound(t *testing.T) {
+	rr, err := httpRequestAPI(t, "POST", "/api/checkin/missingcheckin/fail", strings.NewReader("job failed"))
+	assert.Nil(t, err)
+	assert.Equal(t, 404, rr.Code)
+}
+
+func TestApiMaintenanceHandlers(t *testing.T) {
+	data := `{"service": 0, "message": "Weekly deploy", "start": "2018-10-01T02:00:00Z", "end": "2018-10-01T03:00:00Z", "recurring": "weekly"}`
+	rr, err := httpRequestAPI(t, "POST", "/api/maintenance", strings.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var maintenance types.Maintenance
+	formatJSON(rr.Body.String(), &maintenance)
+	assert.NotZero(t, maintenance.Id)
+	assert.Equal(t, "weekly", maintenance.Recurring)
+
+	rr, err = httpRequestAPI(t, "GET", "/api/maintenance", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Weekly deploy")
+
+	rr, err = httpRequestAPI(t, "DELETE", fmt.Sprintf("/api/maintenance/%v", maintenance.Id), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+}
+
+func TestApiCreateMaintenanceHandlerInvalid(t *testing.T) {
+	data := `{"service": 0, "start": "2018-10-01T03:00:00Z", "end": "2018-10-01T02:00:00Z"}`
+	rr, err := httpRequestAPI(t, "POST", "/api/maintenance", strings.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, rr.Code)
+}
+
+func TestApiIncidentHandlers(t *testing.T) {
+	rr, err := httpRequestAPI(t, "POST", "/api/incidents", strings.NewReader(`{"title": "Elevated error rates", "status": "investigating", "services": [2]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var incident types.Incident
+	formatJSON(rr.Body.String(), &incident)
+	assert.NotZero(t, incident.Id)
+	assert.Equal(t, []int64{2}, incident.Services)
+
+	url := fmt.Sprintf("/api/incidents/%v", incident.Id)
+	rr, err = httpRequestAPI(t, "POST", url+"/updates", strings.NewReader(`{"status": "identified", "message": "A bad deploy has been rolled back"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	formatJSON(rr.Body.String(), &incident)
+	assert.Equal(t, "identified", incident.Status)
+	assert.Len(t, incident.Updates, 1)
+
+	rr, err = httpRequestAPI(t, "GET", "/", nil)
+	assert.Nil(t, err)
+	assert.Contains(t, rr.Body.String(), "A bad deploy has been rolled back")
+
+	rr, err = httpRequestAPI(t, "POST", url+"/updates", strings.NewReader(`{"status": "unknown", "message": "bad status"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, rr.Code)
+
+	rr, err = httpRequestAPI(t, "POST", url+"/acknowledge", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	formatJSON(rr.Body.String(), &incident)
+	assert.Equal(t, "api", incident.AcknowledgedBy)
+
+	rr, err = httpRequestAPI(t, "DELETE", url, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+
+	rr, err = httpRequestAPI(t, "GET", url, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 404, rr.Code)
+}
+
+func TestApiServicePauseHandlers(t *testing.T) {
+	rr, err := httpRequestAPI(t, "POST", "/api/services/2/pause", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var service types.Service
+	formatJSON(rr.Body.String(), &service)
+	assert.True(t, service.Paused)
+
+	rr, err = httpRequestAPI(t, "POST", "/api/services/2/resume", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	formatJSON(rr.Body.String(), &service)
+	assert.False(t, service.Paused)
+
+	rr, err = httpRequestAPI(t, "POST", "/api/services/9999/pause", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 404, rr.Code)
+}
+
+func TestApiTokenHandlers(t *testing.T) {
+	rr, err := httpRequestAPI(t, "POST", "/api/users/2/tokens", strings.NewReader(`{"name": "Prometheus", "scopes": "metrics"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var token types.Token
+	formatJSON(rr.Body.String(), &token)
+	assert.NotZero(t, token.Id)
+	assert.NotEmpty(t, token.Value)
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer "+token.Value)
+	assert.True(t, hasTokenScope(req, types.RoleViewer, types.ScopeMetrics))
+	assert.False(t, hasTokenScope(req, types.RoleViewer, types.ScopeRead))
+
+	rr, err = httpRequestAPI(t, "GET", "/api/users/2/tokens", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Prometheus")
+	assert.NotContains(t, rr.Body.String(), token.Value)
+
+	rr, err = httpRequestAPI(t, "POST", "/api/users/2/tokens", strings.NewReader(`{"name": "Bad", "scopes": "everything"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, rr.Code)
+
+	rr, err = httpRequestAPI(t, "DELETE", fmt.Sprintf("/api/users/2/tokens/%v", token.Id), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	assert.False(t, hasTokenScope(req, types.RoleViewer, types.ScopeMetrics))
+}
+
+func TestApiUpdateServiceDependencyCycle(t *testing.T) {
+	data := `{"name": "Updated Service", "domain": "https://google.com", "expected_status": 200, "check_interval": 60, "type": "http", "method": "GET", "parent_id": 2}`
+	rr, err := httpRequestAPI(t, "POST", "/api/services/2", strings.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, rr.Code)
+}
+
+func TestApiServiceSLOHandler(t *testing.T) {
+	rr, err := httpRequestAPI(t, "GET", "/api/services/2/slo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 404, rr.Code)
+	service := core.SelectService(2)
+	service.SloTarget = 99.9
+	defer func() { service.SloTarget = 0 }()
+	rr, err = httpRequestAPI(t, "GET", "/api/services/2/slo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var slo types.SLO
+	err = json.Unmarshal(rr.Body.Bytes(), &slo)
+	assert.Nil(t, err)
+	assert.Equal(t, 99.9, slo.Target)
+	assert.Equal(t, 30, slo.Window)
+	assert.Equal(t, 0.1, slo.ErrorBudget)
+}
+
+func TestApiServiceOutagesHandler(t *testing.T) {
+	rr, err := httpRequestAPI(t, "GET", "/api/services/2/outages?limit=5", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, rr.Code)
+	var outages []*types.Outage
+	err = json.Unmarshal(rr.Body.Bytes(), &outages)
+	assert.Nil(t, err)
+	assert.True(t, len(outages) <= 5)
+	rr, err = httpRequestAPI(t, "GET", "/api/services/999/outages", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 404, rr.Code)
+}
+
 func httpRequestAPI(t *testing.T, method, url string, body io.Reader) (*httptest.ResponseRecorder, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
@@ -43,16 +43,25 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 	if Store == nil {
 		resetCookies()
 	}
+	if core.CoreApp.PasswordLoginDisabled() && !core.CoreApp.LDAPEnabled() {
+		err := core.ErrorResponse{Error: "Password login is disabled, sign in with single sign-on."}
+		executeResponse(w, r, "login.html", err, nil)
+		return
+	}
 	session, _ := Store.Get(r, COOKIE_KEY)
 	r.ParseForm()
 	username := r.PostForm.Get("username")
 	password := r.PostForm.Get("password")
-	user, auth := core.AuthUser(username, password)
+	var user *core.User
+	var auth bool
+	if !core.CoreApp.PasswordLoginDisabled() {
+		user, auth = core.AuthUser(username, password)
+	}
+	if !auth && core.CoreApp.LDAPEnabled() {
+		user, auth = core.CoreApp.AuthLDAP(username, password)
+	}
 	if auth {
-		session.Values["authenticated"] = true
-		session.Values["user_id"] = user.Id
-		session.Save(r, w)
-		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		loginUser(w, r, session, user, false)
 	} else {
 		err := core.ErrorResponse{Error: "Incorrect login information submitted, try again."}
 		executeResponse(w, r, "login.html", err, nil)
@@ -76,7 +85,7 @@ func helpHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func logsHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -92,7 +101,7 @@ func logsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func logsLineHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -107,7 +116,7 @@ type exportData struct {
 }
 
 func exportHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
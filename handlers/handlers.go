@@ -16,6 +16,10 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/sessions"
@@ -81,7 +85,139 @@ func IsAuthenticated(r *http.Request) bool {
 	if session.Values["authenticated"] == nil {
 		return false
 	}
-	return session.Values["authenticated"].(bool)
+	if !session.Values["authenticated"].(bool) {
+		return false
+	}
+	// when two-factor authentication is required, users that logged in before they enabled it have to login again
+	if core.CoreApp.RequireTwoFactor {
+		user := sessionUser(r)
+		return user != nil && !core.CoreApp.TwoFactorRequired(user, session.Values["sso"] == true)
+	}
+	return true
+}
+
+// IsOperator returns true if the HTTP request is from a user that can acknowledge incidents and pause services
+func IsOperator(r *http.Request) bool {
+	return hasRole(r, types.RoleOperator)
+}
+
+// IsAdmin returns true if the HTTP request is from a user that can manage services, users, notifiers and settings
+func IsAdmin(r *http.Request) bool {
+	return hasRole(r, types.RoleAdmin)
+}
+
+// hasRole returns true if the HTTP request is authenticated by a user with the role, or a role with more access
+func hasRole(r *http.Request, role string) bool {
+	if !IsAuthenticated(r) {
+		return false
+	}
+	user := sessionUser(r)
+	if user == nil {
+		return false
+	}
+	return user.HasRole(role)
+}
+
+type contextKey int
+
+const sessionUserKey contextKey = iota
+
+// requestUser is the user logged into a HTTP request's session, it's only loaded once for the handler and templates
+type requestUser struct {
+	loaded bool
+	user   *core.User
+}
+
+// sessionUserMiddleware will add a requestUser to the HTTP request's context for sessionUser
+func sessionUserMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), sessionUserKey, &requestUser{})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// sessionUser returns the user logged into the HTTP request's session, or nil if there isn't one. The user is
+// loaded from the database the first time it's needed for the request.
+func sessionUser(r *http.Request) *core.User {
+	cached, ok := r.Context().Value(sessionUserKey).(*requestUser)
+	if ok && cached.loaded {
+		return cached.user
+	}
+	user := loadSessionUser(r)
+	if ok {
+		cached.loaded = true
+		cached.user = user
+	}
+	return user
+}
+
+// loadSessionUser returns the user logged into the HTTP request's session from the database
+func loadSessionUser(r *http.Request) *core.User {
+	if Store == nil {
+		return nil
+	}
+	session, err := Store.Get(r, COOKIE_KEY)
+	if err != nil {
+		return nil
+	}
+	id, ok := session.Values["user_id"].(int64)
+	if !ok {
+		return nil
+	}
+	user, err := core.SelectUser(id)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// csrfToken returns the token that forms of a logged in session have to send back in the 'csrf' field, it's saved
+// into the session the first time a page is rendered for it
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if Store == nil {
+		return ""
+	}
+	session, err := Store.Get(r, COOKIE_KEY)
+	if err != nil || session.Values["authenticated"] != true {
+		return ""
+	}
+	if token, ok := session.Values["csrf"].(string); ok && token != "" {
+		return token
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		utils.Log(3, fmt.Sprintf("Issue creating CSRF token: %v", err))
+		return ""
+	}
+	token := hex.EncodeToString(b)
+	session.Values["csrf"] = token
+	session.Save(r, w)
+	return token
+}
+
+// validCSRF returns true if the form was sent with the session's CSRF token
+func validCSRF(r *http.Request) bool {
+	if Store == nil {
+		return false
+	}
+	session, err := Store.Get(r, COOKIE_KEY)
+	if err != nil {
+		return false
+	}
+	token, _ := session.Values["csrf"].(string)
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(r.PostFormValue("csrf"))) == 1
+}
+
+// requestUsername returns the username of the HTTP request's session user or API token's user, requests
+// authorized by the API secret don't have a user and return 'api'
+func requestUsername(r *http.Request) string {
+	if user := sessionUser(r); user != nil {
+		return user.Username
+	}
+	if _, user, ok := core.AuthToken(bearerToken(r)); ok {
+		return user.Username
+	}
+	return "api"
 }
 
 var handlerFuncs = func(w http.ResponseWriter, r *http.Request) template.FuncMap {
@@ -95,6 +231,12 @@ var handlerFuncs = func(w http.ResponseWriter, r *http.Request) template.FuncMap
 		"Auth": func() bool {
 			return IsAuthenticated(r)
 		},
+		"IsOperator": func() bool {
+			return IsOperator(r)
+		},
+		"IsAdmin": func() bool {
+			return IsAdmin(r)
+		},
 		"VERSION": func() string {
 			return core.VERSION
 		},
@@ -128,6 +270,9 @@ var handlerFuncs = func(w http.ResponseWriter, r *http.Request) template.FuncMap
 		"URL": func() string {
 			return r.URL.String()
 		},
+		"CSRF": func() string {
+			return csrfToken(w, r)
+		},
 		"CHART_DATA": func() string {
 			return ""
 		},
@@ -183,6 +328,8 @@ func executeResponse(w http.ResponseWriter, r *http.Request, file string, data i
 
 	t.Lookup("chartIndex").Funcs(handlerFuncs(w, r))
 
+	// the session is saved before the page is written, so forms can use the CSRF token
+	csrfToken(w, r)
 	err = t.Execute(w, data)
 	if err != nil {
 		utils.Log(4, err)
@@ -21,7 +21,9 @@ import (
 	"github.com/hunterlong/statup/core/notifier"
 	_ "github.com/hunterlong/statup/notifiers"
 	"github.com/hunterlong/statup/source"
+	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -29,6 +31,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestResetHandlerDatabase(t *testing.T) {
@@ -138,6 +141,7 @@ func TestServiceChartHandler(t *testing.T) {
 func TestDashboardHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/dashboard", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -172,9 +176,52 @@ func TestBadLoginHandler(t *testing.T) {
 	assert.Equal(t, 200, rr.Code)
 }
 
+func TestOIDCLoginHandlers(t *testing.T) {
+	req, err := http.NewRequest("GET", "/oauth/login", nil)
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 303, rr.Code)
+
+	core.CoreApp.OidcIssuer = "http://127.0.0.1:1"
+	core.CoreApp.OidcClientId = "statup"
+	core.CoreApp.DisablePasswords = true
+	defer func() {
+		core.CoreApp.OidcIssuer = ""
+		core.CoreApp.OidcClientId = ""
+		core.CoreApp.DisablePasswords = false
+	}()
+
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Single sign-on is not available right now")
+	assert.Contains(t, rr.Body.String(), "Sign in with Single Sign-On")
+	assert.NotContains(t, rr.Body.String(), `name="password"`)
+
+	req, err = http.NewRequest("GET", "/oauth/callback?code=code&state=forged", nil)
+	assert.Nil(t, err)
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Single sign-on was not completed")
+
+	form := url.Values{}
+	form.Add("username", "admin")
+	form.Add("password", "password123")
+	req, err = http.NewRequest("POST", "/dashboard", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Nil(t, err)
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Password login is disabled")
+}
+
 func TestServicesHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/services", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -193,6 +240,7 @@ func TestCreateUserHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/users", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -208,11 +256,13 @@ func TestEditUserHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/user/2", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 
 	req, err = http.NewRequest("GET", "/users", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr = httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -225,6 +275,7 @@ func TestEditUserHandler(t *testing.T) {
 func TestDeleteUserHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/user/2/delete", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -234,6 +285,7 @@ func TestDeleteUserHandler(t *testing.T) {
 func TestUsersHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/users", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -248,6 +300,7 @@ func TestUsersHandler(t *testing.T) {
 func TestUsersEditHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/user/1", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -263,6 +316,7 @@ func TestUsersEditHandler(t *testing.T) {
 func TestSettingsHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/settings", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -272,9 +326,223 @@ func TestSettingsHandler(t *testing.T) {
 	assert.True(t, isRouteAuthenticated(req))
 }
 
+func TestTwoFactorHandlers(t *testing.T) {
+	req, err := http.NewRequest("GET", "/login/2fa", nil)
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 303, rr.Code)
+
+	form := url.Values{}
+	form.Add("code", "123456")
+	req, err = http.NewRequest("POST", "/login/2fa", strings.NewReader(form.Encode()))
+	assert.Nil(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 303, rr.Code)
+
+	req, err = http.NewRequest("GET", "/user/1", nil)
+	assert.Nil(t, err)
+	loginSession(t, req, 1)
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Two-factor authentication is not enabled.")
+
+	core.CoreApp.OidcIssuer = "http://127.0.0.1:1"
+	core.CoreApp.OidcClientId = "statup"
+	req, err = http.NewRequest("GET", "/account", nil)
+	assert.Nil(t, err)
+	loginSession(t, req, 1)
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	core.CoreApp.OidcIssuer = ""
+	core.CoreApp.OidcClientId = ""
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<title>Statup | Account</title>")
+	assert.Contains(t, rr.Body.String(), `<a class="nav-link" href="/account">Account</a>`)
+	assert.Contains(t, rr.Body.String(), "Enable Two-Factor Authentication")
+	assert.Contains(t, rr.Body.String(), `href="/oauth/login?link=1"`)
+	assert.True(t, isRouteAuthenticated(req))
+
+	req, err = http.NewRequest("GET", "/settings", nil)
+	assert.Nil(t, err)
+	loginSession(t, req, 1)
+	rr = httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), `name="require_two_factor"`)
+	assert.Contains(t, rr.Body.String(), `name="trust_sso_two_factor"`)
+}
+
+func TestIsSessionUser(t *testing.T) {
+	req, err := http.NewRequest("GET", "/user/1", nil)
+	assert.Nil(t, err)
+	assert.False(t, isSessionUser(req, 1))
+	session, err := Store.Get(req, COOKIE_KEY)
+	assert.Nil(t, err)
+	session.Values["authenticated"] = true
+	session.Values["user_id"] = int64(1)
+	assert.True(t, isSessionUser(req, 1))
+	assert.False(t, isSessionUser(req, 2))
+}
+
+func TestSessionUserMiddleware(t *testing.T) {
+	req, err := http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+	loginSession(t, req, 1)
+	var first, second *core.User
+	handler := sessionUserMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first = sessionUser(r)
+		second = sessionUser(r)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NotNil(t, first)
+	assert.True(t, first == second)
+}
+
+// testCSRF is the CSRF token of the sessions created by loginSession
+const testCSRF = "testcsrftoken"
+
+// loginSession adds the session cookie of the logged in user to the request
+func loginSession(t *testing.T, req *http.Request, id int64) {
+	login, err := http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+	session, err := Store.Get(login, COOKIE_KEY)
+	assert.Nil(t, err)
+	session.Values["authenticated"] = true
+	session.Values["user_id"] = id
+	session.Values["csrf"] = testCSRF
+	rr := httptest.NewRecorder()
+	assert.Nil(t, session.Save(login, rr))
+	for _, cookie := range rr.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+}
+
+func TestResetTwoFactorHandler(t *testing.T) {
+	user := core.ReturnUser(&types.User{
+		Username: "resettwofactor",
+		Password: "password123",
+		Email:    "resettwofactor@email.com",
+	})
+	userId, err := user.Create()
+	assert.Nil(t, err)
+	key, err := user.TwoFactorKey()
+	assert.Nil(t, err)
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	assert.Nil(t, err)
+	_, err = user.EnableTwoFactor(code)
+	assert.Nil(t, err)
+	path := fmt.Sprintf("/user/%v/2fa/reset", userId)
+
+	req, err := http.NewRequest("GET", path, nil)
+	assert.Nil(t, err)
+	loginSession(t, req, 1)
+	rr := httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 405, rr.Code)
+
+	for _, token := range []string{"", "wrongtoken", testCSRF} {
+		form := url.Values{}
+		form.Add("csrf", token)
+		req, err = http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		assert.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		loginSession(t, req, 1)
+		rr = httptest.NewRecorder()
+		Router().ServeHTTP(rr, req)
+		assert.Equal(t, 303, rr.Code)
+		user, err = core.SelectUser(userId)
+		assert.Nil(t, err)
+		assert.Equal(t, token != testCSRF, user.TotpEnabled)
+	}
+	assert.Nil(t, user.Delete())
+}
+
+func TestRevokeTokenHandler(t *testing.T) {
+	token := core.ReturnToken(&types.Token{User: 1, Name: "Revoke", Scopes: types.ScopeRead})
+	_, err := token.Create()
+	assert.Nil(t, err)
+	path := fmt.Sprintf("/user/1/tokens/%v/revoke", token.Id)
+
+	req, err := http.NewRequest("GET", path, nil)
+	assert.Nil(t, err)
+	loginSession(t, req, 1)
+	rr := httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	assert.Equal(t, 405, rr.Code)
+
+	for _, csrf := range []string{"", testCSRF} {
+		form := url.Values{}
+		form.Add("csrf", csrf)
+		req, err = http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		assert.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		loginSession(t, req, 1)
+		rr = httptest.NewRecorder()
+		Router().ServeHTTP(rr, req)
+		assert.Equal(t, 303, rr.Code)
+		_, err = core.SelectToken(token.Id)
+		assert.Equal(t, csrf != testCSRF, err == nil)
+	}
+}
+
+func TestUserRoles(t *testing.T) {
+	viewer := core.ReturnUser(&types.User{
+		Username: "viewer",
+		Password: "password123",
+		Email:    "viewer@email.com",
+		Role:     types.RoleViewer,
+	})
+	viewerId, err := viewer.Create()
+	assert.Nil(t, err)
+	operator := core.ReturnUser(&types.User{
+		Username: "operator",
+		Password: "password123",
+		Email:    "operator@email.com",
+		Role:     types.RoleOperator,
+	})
+	operatorId, err := operator.Create()
+	assert.Nil(t, err)
+
+	routes := []struct {
+		method string
+		path   string
+		user   int64
+		code   int
+	}{
+		{"GET", "/settings", viewerId, 303},
+		{"GET", "/settings", operatorId, 303},
+		{"GET", "/settings", 1, 200},
+		{"GET", "/users", operatorId, 303},
+		{"GET", "/services", viewerId, 200},
+		{"GET", "/api/services", viewerId, 200},
+		{"GET", "/api/users", viewerId, 401},
+		{"GET", "/api/users", operatorId, 401},
+		{"POST", "/api/services/1/pause", viewerId, 401},
+		{"POST", "/api/services/1/pause", operatorId, 200},
+		{"POST", "/api/services/1/resume", operatorId, 200},
+		{"DELETE", "/api/services/1", operatorId, 401},
+	}
+	for _, route := range routes {
+		req, err := http.NewRequest(route.method, route.path, nil)
+		assert.Nil(t, err)
+		loginSession(t, req, route.user)
+		rr := httptest.NewRecorder()
+		Router().ServeHTTP(rr, req)
+		assert.Equal(t, route.code, rr.Code, "%v %v as user %v", route.method, route.path, route.user)
+	}
+	assert.NotNil(t, core.SelectService(1))
+	assert.Nil(t, viewer.Delete())
+	assert.Nil(t, operator.Delete())
+}
+
 func TestHelpHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/help", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -299,6 +567,7 @@ func TestCreateHTTPServiceHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/services", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -320,6 +589,7 @@ func TestCreateTCPerviceHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/services", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -329,6 +599,7 @@ func TestCreateTCPerviceHandler(t *testing.T) {
 func TestServicesHandler2(t *testing.T) {
 	req, err := http.NewRequest("GET", "/services", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -343,6 +614,7 @@ func TestServicesHandler2(t *testing.T) {
 func TestViewHTTPServicesHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/service/6", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -354,6 +626,7 @@ func TestViewHTTPServicesHandler(t *testing.T) {
 func TestViewTCPServicesHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/service/7", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -362,9 +635,26 @@ func TestViewTCPServicesHandler(t *testing.T) {
 	assert.Contains(t, body, "Statup  made with ❤️")
 }
 
+func TestCheckinCreateInvalidHandler(t *testing.T) {
+	form := url.Values{}
+	form.Add("interval", "60")
+	form.Add("schedule", "not a schedule")
+	req, err := http.NewRequest("POST", "/service/7/checkin", strings.NewReader(form.Encode()))
+	assert.Nil(t, err)
+	loginSession(t, req, 1)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	Router().ServeHTTP(rr, req)
+	body := rr.Body.String()
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, body, "<title>Statup | Local Postgres Service</title>")
+	assert.Contains(t, body, "Checkin could not be saved")
+}
+
 func TestServicesDeleteFailuresHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/service/7/delete_failures", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -374,6 +664,7 @@ func TestServicesDeleteFailuresHandler(t *testing.T) {
 func TestFailingServicesDeleteFailuresHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/service/1/delete_failures", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -394,11 +685,13 @@ func TestServicesUpdateHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/service/6", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 
 	req, err = http.NewRequest("GET", "/service/6", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr = httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -410,6 +703,7 @@ func TestServicesUpdateHandler(t *testing.T) {
 func TestDeleteServiceHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/service/7/delete", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -419,6 +713,7 @@ func TestDeleteServiceHandler(t *testing.T) {
 func TestLogsHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/logs", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -431,6 +726,7 @@ func TestLogsHandler(t *testing.T) {
 func TestLogsLineHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/logs/line", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -447,6 +743,7 @@ func TestSaveSettingsHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/settings", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -456,6 +753,7 @@ func TestSaveSettingsHandler(t *testing.T) {
 func TestViewSettingsHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/settings", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -469,6 +767,7 @@ func TestViewSettingsHandler(t *testing.T) {
 func TestSaveAssetsHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/settings/build", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -481,6 +780,7 @@ func TestSaveAssetsHandler(t *testing.T) {
 func TestDeleteAssetsHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/settings/delete_assets", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -492,6 +792,7 @@ func TestPrometheusHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/metrics", nil)
 	req.Header.Set("Authorization", core.CoreApp.ApiSecret)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -519,6 +820,7 @@ func TestSaveNotificationHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/settings/notifier/email", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -531,6 +833,7 @@ func TestSaveNotificationHandler(t *testing.T) {
 func TestViewNotificationSettingsHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/settings", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -553,6 +856,7 @@ func TestSaveFooterHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/settings", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -560,6 +864,7 @@ func TestSaveFooterHandler(t *testing.T) {
 
 	req, err = http.NewRequest("GET", "/", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr = httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	body := rr.Body.String()
@@ -586,6 +891,7 @@ func TestLogoutHandler(t *testing.T) {
 func TestBuildAssetsHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/settings/build", nil)
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -603,6 +909,7 @@ func TestSaveSassHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/settings/css", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 303, rr.Code)
@@ -617,6 +924,7 @@ func TestReorderServiceHandler(t *testing.T) {
 	req, err := http.NewRequest("POST", "/services/reorder", strings.NewReader(data))
 	req.Header.Set("Content-Type", "application/json")
 	assert.Nil(t, err)
+	loginSession(t, req, 1)
 	rr := httptest.NewRecorder()
 	Router().ServeHTTP(rr, req)
 	assert.Equal(t, 200, rr.Code)
@@ -652,6 +960,7 @@ func TestCreateBulkServices(t *testing.T) {
 		req, err := http.NewRequest("POST", "/services", strings.NewReader(form.Encode()))
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		assert.Nil(t, err)
+		loginSession(t, req, 1)
 		rr := httptest.NewRecorder()
 		Router().ServeHTTP(rr, req)
 		assert.Equal(t, 303, rr.Code)
@@ -662,6 +971,7 @@ func TestCreateBulkServices(t *testing.T) {
 func isRouteAuthenticated(req *http.Request) bool {
 	os.Setenv("GO_ENV", "production")
 	rr := httptest.NewRecorder()
+	req.Header.Del("Cookie")
 	req.Header.Set("Authorization", "badkey")
 	Router().ServeHTTP(rr, req)
 	code := rr.Code
@@ -0,0 +1,248 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/hunterlong/statup/core"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"net/http"
+)
+
+func createIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsOperator(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	var services []int64
+	for _, id := range r.PostForm["services"] {
+		services = append(services, utils.StringInt(id))
+	}
+	incident := core.ReturnIncident(&types.Incident{
+		Title:    r.PostForm.Get("title"),
+		Status:   r.PostForm.Get("status"),
+		Services: services,
+	})
+	_, err := incident.Create()
+	if err != nil {
+		utils.Log(3, err)
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	message := r.PostForm.Get("message")
+	if message != "" {
+		incident.AddUpdate(&types.IncidentUpdate{Status: incident.Status, Message: message})
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func createIncidentUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsOperator(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	_, err = incident.AddUpdate(&types.IncidentUpdate{
+		Status:  r.PostForm.Get("status"),
+		Message: r.PostForm.Get("message"),
+	})
+	if err != nil {
+		utils.Log(3, err)
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func acknowledgeIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsOperator(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err == nil {
+		incident.Acknowledge(requestUsername(r))
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func deleteIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsAdmin(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err == nil {
+		incident.Delete()
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func apiAllIncidentsHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIAuthorized(r) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	incidents := core.AllIncidents()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incidents)
+}
+
+func apiIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIAuthorized(r) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+func apiCreateIncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIRole(r, types.RoleOperator) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	var incident *types.Incident
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&incident)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	incident.Updates = nil
+	_, err = core.ReturnIncident(incident).Create()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+func apiIncidentUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIRole(r, types.RoleOperator) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&incident)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	incident.Id = utils.StringInt(vars["id"])
+	err = incident.Update()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+func apiCreateIncidentUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIRole(r, types.RoleOperator) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	var update *types.IncidentUpdate
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&update)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	_, err = incident.AddUpdate(update)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+func apiIncidentAcknowledgeHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIRole(r, types.RoleOperator) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	err = incident.Acknowledge(requestUsername(r))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incident)
+}
+
+func apiIncidentDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIRole(r, types.RoleAdmin) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	incident, err := core.SelectIncident(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	err = incident.Delete()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	output := ApiResponse{
+		Object: "incident",
+		Method: "delete",
+		Id:     incident.Id,
+		Status: "success",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(output)
+}
@@ -0,0 +1,134 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/hunterlong/statup/core"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"net/http"
+	"time"
+)
+
+// parseMaintenanceTime will parse a 'datetime-local' form value in Statup's timezone
+func parseMaintenanceTime(value string) (time.Time, error) {
+	zone := time.FixedZone("", int(core.CoreApp.Timezone*3600))
+	return time.ParseInLocation("2006-01-02T15:04", value, zone)
+}
+
+func createMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsAdmin(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	start, err := parseMaintenanceTime(r.PostForm.Get("start"))
+	if err != nil {
+		utils.Log(2, err)
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+	end, err := parseMaintenanceTime(r.PostForm.Get("end"))
+	if err != nil {
+		utils.Log(2, err)
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+	maintenance := core.ReturnMaintenance(&types.Maintenance{
+		Service:   utils.StringInt(r.PostForm.Get("service")),
+		Message:   r.PostForm.Get("message"),
+		Start:     start.UTC(),
+		End:       end.UTC(),
+		Recurring: r.PostForm.Get("recurring"),
+	})
+	_, err = maintenance.Create()
+	if err != nil {
+		utils.Log(2, err)
+	}
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+func deleteMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsAdmin(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	vars := mux.Vars(r)
+	maintenance, err := core.SelectMaintenance(utils.StringInt(vars["id"]))
+	if err == nil {
+		maintenance.Delete()
+	}
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+func apiAllMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIAuthorized(r) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	maintenance := core.AllMaintenance()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maintenance)
+}
+
+func apiCreateMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIRole(r, types.RoleAdmin) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	var maintenance *types.Maintenance
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&maintenance)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	_, err = core.ReturnMaintenance(maintenance).Create()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maintenance)
+}
+
+func apiMaintenanceDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAPIRole(r, types.RoleAdmin) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	maintenance, err := core.SelectMaintenance(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	err = maintenance.Delete()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	output := ApiResponse{
+		Object: "maintenance",
+		Method: "delete",
+		Id:     maintenance.Id,
+		Status: "success",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(output)
+}
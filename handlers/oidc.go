@@ -0,0 +1,141 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package handlers
+
+import (
+	"fmt"
+	"github.com/hunterlong/statup/core"
+	"github.com/hunterlong/statup/utils"
+	"net/http"
+	"strings"
+)
+
+// oidcRedirectURL returns the URL the provider sends users back to after they login, this URL has to be
+// allowed in the provider's client settings
+func oidcRedirectURL(r *http.Request) string {
+	if core.CoreApp.Domain != "" {
+		return strings.TrimRight(core.CoreApp.Domain, "/") + "/oauth/callback"
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%v://%v/oauth/callback", scheme, r.Host)
+}
+
+// oidcLoginHandler will send the user to the OpenID Connect provider to login. A logged in local user can link
+// their single sign-on account with ?link=1.
+func oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !core.CoreApp.OIDCEnabled() {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if Store == nil {
+		resetCookies()
+	}
+	session, _ := Store.Get(r, COOKIE_KEY)
+	delete(session.Values, "oidc_link")
+	if r.URL.Query().Get("link") == "1" {
+		user := sessionUser(r)
+		if user == nil || !IsAuthenticated(r) {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		session.Values["oidc_link"] = user.Id
+	}
+	authURL, state, nonce, err := core.CoreApp.OIDCAuthURL(oidcRedirectURL(r))
+	if err != nil {
+		utils.Log(3, fmt.Sprintf("Issue starting single sign-on: %v", err))
+		executeResponse(w, r, "login.html", core.ErrorResponse{Error: "Single sign-on is not available right now, try again later."}, nil)
+		return
+	}
+	session.Values["oidc_state"] = state
+	session.Values["oidc_nonce"] = nonce
+	session.Save(r, w)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oidcCallbackHandler will login the user the provider sent back, creating the user if it's their first login,
+// or link the provider's account to the logged in user that started the login with ?link=1
+func oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if !core.CoreApp.OIDCEnabled() {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if Store == nil {
+		resetCookies()
+	}
+	session, _ := Store.Get(r, COOKIE_KEY)
+	state, _ := session.Values["oidc_state"].(string)
+	nonce, _ := session.Values["oidc_nonce"].(string)
+	linkId, linking := session.Values["oidc_link"].(int64)
+	delete(session.Values, "oidc_state")
+	delete(session.Values, "oidc_nonce")
+	delete(session.Values, "oidc_link")
+	query := r.URL.Query()
+	if state == "" || query.Get("state") != state || query.Get("error") != "" {
+		utils.Log(2, fmt.Sprintf("Single sign-on was not completed: %v %v", query.Get("error"), query.Get("error_description")))
+		session.Save(r, w)
+		executeResponse(w, r, "login.html", core.ErrorResponse{Error: "Single sign-on was not completed, try again."}, nil)
+		return
+	}
+	claims, err := core.CoreApp.OIDCExchange(query.Get("code"), oidcRedirectURL(r), nonce)
+	if err != nil {
+		utils.Log(3, fmt.Sprintf("Issue verifying single sign-on login: %v", err))
+		session.Save(r, w)
+		executeResponse(w, r, "login.html", core.ErrorResponse{Error: "Single sign-on login could not be verified, try again."}, nil)
+		return
+	}
+	if linking {
+		session.Save(r, w)
+		oidcLinkUser(w, r, linkId, claims)
+		return
+	}
+	user, err := core.CoreApp.OIDCUser(claims)
+	if err == core.ErrUserExists {
+		utils.Log(2, fmt.Sprintf("Single sign-on user %v has the username of an existing user that is not linked", claims.Username))
+		session.Save(r, w)
+		executeResponse(w, r, "login.html", core.ErrorResponse{Error: "A user with your username already exists, login with your password and link your single sign-on account from your Account page."}, nil)
+		return
+	}
+	if err != nil {
+		utils.Log(3, fmt.Sprintf("Issue logging in single sign-on user %v: %v", claims.Username, err))
+		session.Save(r, w)
+		executeResponse(w, r, "login.html", core.ErrorResponse{Error: "Your account could not be created, contact an admin."}, nil)
+		return
+	}
+	loginUser(w, r, session, user, true)
+}
+
+// oidcLinkUser will link the single sign-on account of the claims to the logged in user that started linking
+func oidcLinkUser(w http.ResponseWriter, r *http.Request, id int64, claims *core.OIDCClaims) {
+	user := sessionUser(r)
+	if user == nil || user.Id != id || !IsAuthenticated(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	page := &twoFactorPage{User: user}
+	if err := core.CoreApp.LinkOIDC(user, claims); err != nil {
+		utils.Log(2, fmt.Sprintf("User %v could not link their single sign-on account: %v", user.Username, err))
+		page.Error = fmt.Sprintf("Single sign-on account could not be linked: %v", err)
+	} else {
+		utils.Log(1, fmt.Sprintf("User %v linked their single sign-on account", user.Username))
+	}
+	if !user.TotpEnabled {
+		twoFactorQRCode(user, page)
+	}
+	executeResponse(w, r, "account.html", page, nil)
+}
@@ -27,7 +27,7 @@ type PluginSelect struct {
 }
 
 func pluginSavedHandler(w http.ResponseWriter, r *http.Request) {
-	auth := IsAuthenticated(r)
+	auth := IsAdmin(r)
 	if !auth {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -44,7 +44,7 @@ func pluginSavedHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func pluginsDownloadHandler(w http.ResponseWriter, r *http.Request) {
-	auth := IsAuthenticated(r)
+	auth := IsAdmin(r)
 	if !auth {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -18,13 +18,14 @@ package handlers
 import (
 	"fmt"
 	"github.com/hunterlong/statup/core"
+	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
 	"net/http"
 	"strings"
 )
 
 //
-//   Use your Statup Secret API Key for Authentication
+//   Use your Statup Secret API Key, or an API token with the 'metrics' scope for Authentication
 //
 //		scrape_configs:
 //		  - job_name: 'statup'
@@ -35,7 +36,7 @@ import (
 
 func prometheusHandler(w http.ResponseWriter, r *http.Request) {
 	utils.Log(1, fmt.Sprintf("Prometheus /metrics Request From IP: %v\n", r.RemoteAddr))
-	if !isAuthorized(r) {
+	if !isAuthorized(r) && !hasTokenScope(r, types.RoleViewer, types.ScopeMetrics) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -54,6 +55,13 @@ func prometheusHandler(w http.ResponseWriter, r *http.Request) {
 		met += fmt.Sprintf("statup_service_online{id=\"%v\" name=\"%v\"} %v\n", v.Id, v.Name, online)
 		met += fmt.Sprintf("statup_service_status_code{id=\"%v\" name=\"%v\"} %v\n", v.Id, v.Name, v.LastStatusCode)
 		met += fmt.Sprintf("statup_service_response_length{id=\"%v\" name=\"%v\"} %v", v.Id, v.Name, len([]byte(v.LastResponse)))
+		if slo := v.SLO(); slo != nil {
+			met += fmt.Sprintf("\nstatup_service_slo_target{id=\"%v\" name=\"%v\"} %v\n", v.Id, v.Name, slo.Target)
+			met += fmt.Sprintf("statup_service_slo_uptime{id=\"%v\" name=\"%v\"} %v\n", v.Id, v.Name, slo.Uptime)
+			met += fmt.Sprintf("statup_service_error_budget_remaining{id=\"%v\" name=\"%v\"} %v\n", v.Id, v.Name, slo.BudgetRemaining)
+			met += fmt.Sprintf("statup_service_error_budget_burn_rate{id=\"%v\" name=\"%v\"} %v\n", v.Id, v.Name, slo.BurnRate)
+			met += fmt.Sprintf("statup_service_latency_p95{id=\"%v\" name=\"%v\"} %v", v.Id, v.Name, slo.LatencyP95)
+		}
 		metrics = append(metrics, met)
 	}
 	output := strings.Join(metrics, "\n")
@@ -61,15 +69,31 @@ func prometheusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(output))
 }
 
-func isAuthorized(r *http.Request) bool {
+// bearerToken returns the token from the HTTP request's Authorization header
+func bearerToken(r *http.Request) string {
 	var token string
 	tokens, ok := r.Header["Authorization"]
 	if ok && len(tokens) >= 1 {
 		token = tokens[0]
 		token = strings.TrimPrefix(token, "Bearer ")
 	}
-	if token == core.CoreApp.ApiSecret {
+	return token
+}
+
+// isAuthorized returns true if the HTTP request uses the Statup API secret
+func isAuthorized(r *http.Request) bool {
+	token := bearerToken(r)
+	if token != "" && token == core.CoreApp.ApiSecret {
 		return true
 	}
 	return false
 }
+
+// hasTokenScope returns true if the HTTP request uses an API token with the scope, that belongs to a user with the role
+func hasTokenScope(r *http.Request, role, scope string) bool {
+	token, user, ok := core.AuthToken(bearerToken(r))
+	if !ok {
+		return false
+	}
+	return token.HasScope(scope) && user.HasRole(role)
+}
@@ -33,6 +33,7 @@ var (
 func Router() *mux.Router {
 	dir := utils.Directory
 	r := mux.NewRouter()
+	r.Use(sessionUserMiddleware)
 	r.Handle("/", http.HandlerFunc(indexHandler))
 	if source.UsingAssets(dir) {
 		indexHandler := http.FileServer(http.Dir(dir + "/assets/"))
@@ -54,6 +55,10 @@ func Router() *mux.Router {
 	r.Handle("/dashboard", http.HandlerFunc(dashboardHandler)).Methods("GET")
 	r.Handle("/dashboard", http.HandlerFunc(loginHandler)).Methods("POST")
 	r.Handle("/logout", http.HandlerFunc(logoutHandler))
+	r.Handle("/login/2fa", http.HandlerFunc(twoFactorLoginHandler)).Methods("GET")
+	r.Handle("/login/2fa", http.HandlerFunc(twoFactorVerifyHandler)).Methods("POST")
+	r.Handle("/oauth/login", http.HandlerFunc(oidcLoginHandler)).Methods("GET")
+	r.Handle("/oauth/callback", http.HandlerFunc(oidcCallbackHandler)).Methods("GET")
 	r.Handle("/services", http.HandlerFunc(servicesHandler)).Methods("GET")
 	r.Handle("/services", http.HandlerFunc(createServiceHandler)).Methods("POST")
 	r.Handle("/services/reorder", http.HandlerFunc(reorderServiceHandler)).Methods("POST")
@@ -62,20 +67,36 @@ func Router() *mux.Router {
 	r.Handle("/service/{id}/edit", http.HandlerFunc(servicesViewHandler))
 	r.Handle("/service/{id}/delete", http.HandlerFunc(servicesDeleteHandler))
 	r.Handle("/service/{id}/delete_failures", http.HandlerFunc(servicesDeleteFailuresHandler)).Methods("GET")
+	r.Handle("/service/{id}/pause", http.HandlerFunc(servicesPauseHandler)).Methods("GET")
+	r.Handle("/service/{id}/resume", http.HandlerFunc(servicesResumeHandler)).Methods("GET")
 	r.Handle("/service/{id}/checkin", http.HandlerFunc(checkinCreateUpdateHandler)).Methods("POST")
+	r.Handle("/incidents", http.HandlerFunc(createIncidentHandler)).Methods("POST")
+	r.Handle("/incident/{id}/update", http.HandlerFunc(createIncidentUpdateHandler)).Methods("POST")
+	r.Handle("/incident/{id}/acknowledge", http.HandlerFunc(acknowledgeIncidentHandler)).Methods("GET")
+	r.Handle("/incident/{id}/delete", http.HandlerFunc(deleteIncidentHandler)).Methods("GET")
+	r.Handle("/account", http.HandlerFunc(accountHandler)).Methods("GET")
+	r.Handle("/account/2fa", http.HandlerFunc(enableTwoFactorHandler)).Methods("POST")
+	r.Handle("/account/2fa/disable", http.HandlerFunc(disableTwoFactorHandler)).Methods("POST")
+	r.Handle("/account/tokens", http.HandlerFunc(accountTokensHandler)).Methods("GET")
 	r.Handle("/users", http.HandlerFunc(usersHandler)).Methods("GET")
 	r.Handle("/users", http.HandlerFunc(createUserHandler)).Methods("POST")
 	r.Handle("/user/{id}", http.HandlerFunc(usersEditHandler)).Methods("GET")
 	r.Handle("/user/{id}", http.HandlerFunc(updateUserHandler)).Methods("POST")
 	r.Handle("/user/{id}/delete", http.HandlerFunc(usersDeleteHandler)).Methods("GET")
+	r.Handle("/user/{id}/2fa/reset", http.HandlerFunc(resetTwoFactorHandler)).Methods("POST")
+	r.Handle("/user/{id}/tokens", http.HandlerFunc(createTokenHandler)).Methods("POST")
+	r.Handle("/user/{id}/tokens/{token}/revoke", http.HandlerFunc(revokeTokenHandler)).Methods("POST")
 	r.Handle("/settings", http.HandlerFunc(settingsHandler)).Methods("GET")
 	r.Handle("/settings", http.HandlerFunc(saveSettingsHandler)).Methods("POST")
+	r.Handle("/settings/auth", http.HandlerFunc(saveAuthSettingsHandler)).Methods("POST")
 	r.Handle("/settings/css", http.HandlerFunc(saveSASSHandler)).Methods("POST")
 	r.Handle("/settings/build", http.HandlerFunc(saveAssetsHandler)).Methods("GET")
 	r.Handle("/settings/delete_assets", http.HandlerFunc(deleteAssetsHandler)).Methods("GET")
 	r.Handle("/settings/notifier/{method}", http.HandlerFunc(saveNotificationHandler)).Methods("POST")
 	r.Handle("/settings/notifier/{method}/test", http.HandlerFunc(testNotificationHandler)).Methods("POST")
 	r.Handle("/settings/export", http.HandlerFunc(exportHandler)).Methods("GET")
+	r.Handle("/settings/maintenance", http.HandlerFunc(createMaintenanceHandler)).Methods("POST")
+	r.Handle("/settings/maintenance/{id}/delete", http.HandlerFunc(deleteMaintenanceHandler)).Methods("GET")
 	r.Handle("/plugins/download/{name}", http.HandlerFunc(pluginsDownloadHandler))
 	r.Handle("/plugins/{name}/save", http.HandlerFunc(pluginSavedHandler)).Methods("POST")
 	r.Handle("/help", http.HandlerFunc(helpHandler))
@@ -87,6 +108,11 @@ func Router() *mux.Router {
 	r.Handle("/api/services", http.HandlerFunc(apiCreateServiceHandler)).Methods("POST")
 	r.Handle("/api/services/{id}", http.HandlerFunc(apiServiceHandler)).Methods("GET")
 	r.Handle("/api/services/{id}/data", http.HandlerFunc(apiServiceDataHandler)).Methods("GET")
+	r.Handle("/api/services/{id}/outages", http.HandlerFunc(apiServiceOutagesHandler)).Methods("GET")
+	r.Handle("/api/services/{id}/slo", http.HandlerFunc(apiServiceSLOHandler)).Methods("GET")
+	r.Handle("/api/services/{id}/probe", http.HandlerFunc(apiServiceProbeHandler)).Methods("POST")
+	r.Handle("/api/services/{id}/pause", http.HandlerFunc(apiServicePauseHandler)).Methods("POST")
+	r.Handle("/api/services/{id}/resume", http.HandlerFunc(apiServiceResumeHandler)).Methods("POST")
 	r.Handle("/api/services/{id}", http.HandlerFunc(apiServiceUpdateHandler)).Methods("POST")
 	r.Handle("/api/services/{id}", http.HandlerFunc(apiServiceDeleteHandler)).Methods("DELETE")
 
@@ -96,11 +122,30 @@ func Router() *mux.Router {
 	r.Handle("/api/users/{id}", http.HandlerFunc(apiUserHandler)).Methods("GET")
 	r.Handle("/api/users/{id}", http.HandlerFunc(apiUserUpdateHandler)).Methods("POST")
 	r.Handle("/api/users/{id}", http.HandlerFunc(apiUserDeleteHandler)).Methods("DELETE")
+	r.Handle("/api/users/{id}/tokens", http.HandlerFunc(apiAllTokensHandler)).Methods("GET")
+	r.Handle("/api/users/{id}/tokens", http.HandlerFunc(apiCreateTokenHandler)).Methods("POST")
+	r.Handle("/api/users/{id}/tokens/{token}", http.HandlerFunc(apiRevokeTokenHandler)).Methods("DELETE")
+
+	// INCIDENT API Routes
+	r.Handle("/api/incidents", http.HandlerFunc(apiAllIncidentsHandler)).Methods("GET")
+	r.Handle("/api/incidents", http.HandlerFunc(apiCreateIncidentHandler)).Methods("POST")
+	r.Handle("/api/incidents/{id}", http.HandlerFunc(apiIncidentHandler)).Methods("GET")
+	r.Handle("/api/incidents/{id}", http.HandlerFunc(apiIncidentUpdateHandler)).Methods("POST")
+	r.Handle("/api/incidents/{id}", http.HandlerFunc(apiIncidentDeleteHandler)).Methods("DELETE")
+	r.Handle("/api/incidents/{id}/updates", http.HandlerFunc(apiCreateIncidentUpdateHandler)).Methods("POST")
+	r.Handle("/api/incidents/{id}/acknowledge", http.HandlerFunc(apiIncidentAcknowledgeHandler)).Methods("POST")
+
+	// MAINTENANCE API Routes
+	r.Handle("/api/maintenance", http.HandlerFunc(apiAllMaintenanceHandler)).Methods("GET")
+	r.Handle("/api/maintenance", http.HandlerFunc(apiCreateMaintenanceHandler)).Methods("POST")
+	r.Handle("/api/maintenance/{id}", http.HandlerFunc(apiMaintenanceDeleteHandler)).Methods("DELETE")
 
 	// Generic API Routes
 	r.Handle("/api", http.HandlerFunc(apiIndexHandler))
 	r.Handle("/api/renew", http.HandlerFunc(apiRenewHandler))
 	r.Handle("/api/checkin/{api}", http.HandlerFunc(apiCheckinHandler))
+	r.Handle("/api/checkin/{api}/start", http.HandlerFunc(apiCheckinStartHandler))
+	r.Handle("/api/checkin/{api}/fail", http.HandlerFunc(apiCheckinFailHandler))
 	r.Handle("/metrics", http.HandlerFunc(prometheusHandler))
 	r.NotFoundHandler = http.HandlerFunc(error404Handler)
 	r.Handle("/tray", http.HandlerFunc(trayHandler))
@@ -25,6 +25,7 @@ import (
 	"github.com/jinzhu/now"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -54,7 +55,7 @@ func renderServiceChartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	service := core.SelectService(utils.StringInt(vars["id"]))
-	data := core.GraphDataRaw(service, start, end, "hour").ToString()
+	data := core.GraphDataRaw(service, start, end, "hour", "avg").ToString()
 
 	out := struct {
 		Services []*core.Service
@@ -74,7 +75,7 @@ func renderServiceChartsHandler(w http.ResponseWriter, r *http.Request) {
 	start := now.BeginningOfDay().UTC()
 
 	for _, s := range services {
-		d := core.GraphDataRaw(s, start, end, "hour").ToString()
+		d := core.GraphDataRaw(s, start, end, "hour", "avg").ToString()
 		data = append(data, d)
 	}
 
@@ -100,7 +101,7 @@ type serviceOrder struct {
 }
 
 func reorderServiceHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -116,7 +117,7 @@ func reorderServiceHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func createServiceHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -129,27 +130,75 @@ func createServiceHandler(w http.ResponseWriter, r *http.Request) {
 	interval, _ := strconv.Atoi(r.PostForm.Get("interval"))
 	port, _ := strconv.Atoi(r.PostForm.Get("port"))
 	timeout, _ := strconv.Atoi(r.PostForm.Get("timeout"))
+	failAfter, _ := strconv.Atoi(r.PostForm.Get("fail_after"))
+	recoverAfter, _ := strconv.Atoi(r.PostForm.Get("recover_after"))
+	retries, _ := strconv.Atoi(r.PostForm.Get("retries"))
+	quorum, _ := strconv.Atoi(r.PostForm.Get("quorum"))
+	retryDelay, _ := strconv.Atoi(r.PostForm.Get("retry_delay"))
 	checkType := r.PostForm.Get("check_type")
 	postData := r.PostForm.Get("post_data")
+	headers := r.PostForm.Get("headers")
+	contentType := r.PostForm.Get("content_type")
+	skipVerify := r.PostForm.Get("skip_verify") == "on"
+	noRedirects := r.PostForm.Get("no_redirects") == "on"
 	order, _ := strconv.Atoi(r.PostForm.Get("order"))
-
-	if checkType == "http" && status == 0 {
-		status = 200
-	}
+	packetCount, _ := strconv.Atoi(r.PostForm.Get("packet_count"))
+	maxPacketLoss, _ := strconv.Atoi(r.PostForm.Get("max_packet_loss"))
+	dnsRecord := r.PostForm.Get("dns_record")
+	dnsResolver := r.PostForm.Get("dns_resolver")
+	grpcService := r.PostForm.Get("grpc_service")
+	grpcTls := r.PostForm.Get("grpc_tls") == "on"
+	certExpiryWarn, _ := strconv.Atoi(r.PostForm.Get("cert_expiry_warn"))
+	groupName := strings.TrimSpace(r.PostForm.Get("group_name"))
+	parentId := utils.StringInt(r.PostForm.Get("parent_id"))
+	sloTarget, _ := strconv.ParseFloat(r.PostForm.Get("slo_target"), 64)
+	sloWindow, _ := strconv.Atoi(r.PostForm.Get("slo_window"))
+	sloLatency, _ := strconv.Atoi(r.PostForm.Get("slo_latency"))
+	sloBurnRate, _ := strconv.ParseFloat(r.PostForm.Get("slo_burn_rate"), 64)
+	hitsRetention, _ := strconv.Atoi(r.PostForm.Get("hits_retention"))
+	failuresRetention, _ := strconv.Atoi(r.PostForm.Get("failures_retention"))
 
 	service := core.ReturnService(&types.Service{
-		Name:           name,
-		Domain:         domain,
-		Method:         method,
-		Expected:       expected,
-		ExpectedStatus: status,
-		Interval:       interval,
-		Type:           checkType,
-		Port:           port,
-		PostData:       postData,
-		Timeout:        timeout,
-		Order:          order,
+		Name:              name,
+		Domain:            domain,
+		Method:            method,
+		Expected:          expected,
+		ExpectedStatus:    status,
+		Interval:          interval,
+		Type:              checkType,
+		Port:              port,
+		PostData:          postData,
+		Headers:           headers,
+		ContentType:       contentType,
+		SkipVerify:        skipVerify,
+		NoRedirects:       noRedirects,
+		Timeout:           timeout,
+		FailAfter:         failAfter,
+		RecoverAfter:      recoverAfter,
+		Retries:           retries,
+		Quorum:            quorum,
+		RetryDelay:        retryDelay,
+		Order:             order,
+		PacketCount:       packetCount,
+		MaxPacketLoss:     maxPacketLoss,
+		DnsRecord:         dnsRecord,
+		DnsResolver:       dnsResolver,
+		GrpcService:       grpcService,
+		GrpcTls:           grpcTls,
+		CertExpiryWarn:    certExpiryWarn,
+		GroupName:         groupName,
+		ParentId:          parentId,
+		SloTarget:         sloTarget,
+		SloWindow:         sloWindow,
+		SloLatency:        sloLatency,
+		SloBurnRate:       sloBurnRate,
+		HitsRetention:     hitsRetention,
+		FailuresRetention: failuresRetention,
 	})
+	if !service.ValidParent(parentId) {
+		utils.Log(2, fmt.Sprintf("Service %v cannot depend on service %v", name, parentId))
+		service.ParentId = 0
+	}
 	_, err := service.Create(true)
 	if err != nil {
 		utils.Log(3, fmt.Sprintf("Error starting %v check routine. %v", service.Name, err))
@@ -159,7 +208,7 @@ func createServiceHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func servicesDeleteHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -194,20 +243,27 @@ func servicesViewHandler(w http.ResponseWriter, r *http.Request) {
 		end = time.Unix(endField, 0)
 	}
 
-	data := core.GraphDataRaw(serv, start, end, "hour")
+	executeServiceView(w, r, serv, start, end, "")
+}
+
+// executeServiceView will render the service page with the service's chart between start and end, formError
+// is shown on the checkin form when a checkin could not be saved
+func executeServiceView(w http.ResponseWriter, r *http.Request, serv *core.Service, start, end time.Time, formError string) {
+	data := core.GraphDataRaw(serv, start, end, "hour", "avg")
 
 	out := struct {
 		Service *core.Service
 		Start   int64
 		End     int64
 		Data    string
-	}{serv, start.Unix(), end.Unix(), data.ToString()}
+		Error   string
+	}{serv, start.Unix(), end.Unix(), data.ToString(), formError}
 
 	executeResponse(w, r, "service.html", out, nil)
 }
 
 func servicesUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -222,9 +278,33 @@ func servicesUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	interval, _ := strconv.Atoi(r.PostForm.Get("interval"))
 	port, _ := strconv.Atoi(r.PostForm.Get("port"))
 	timeout, _ := strconv.Atoi(r.PostForm.Get("timeout"))
+	failAfter, _ := strconv.Atoi(r.PostForm.Get("fail_after"))
+	recoverAfter, _ := strconv.Atoi(r.PostForm.Get("recover_after"))
+	retries, _ := strconv.Atoi(r.PostForm.Get("retries"))
+	quorum, _ := strconv.Atoi(r.PostForm.Get("quorum"))
+	retryDelay, _ := strconv.Atoi(r.PostForm.Get("retry_delay"))
 	checkType := r.PostForm.Get("check_type")
 	postData := r.PostForm.Get("post_data")
+	headers := r.PostForm.Get("headers")
+	contentType := r.PostForm.Get("content_type")
+	skipVerify := r.PostForm.Get("skip_verify") == "on"
+	noRedirects := r.PostForm.Get("no_redirects") == "on"
 	order, _ := strconv.Atoi(r.PostForm.Get("order"))
+	packetCount, _ := strconv.Atoi(r.PostForm.Get("packet_count"))
+	maxPacketLoss, _ := strconv.Atoi(r.PostForm.Get("max_packet_loss"))
+	dnsRecord := r.PostForm.Get("dns_record")
+	dnsResolver := r.PostForm.Get("dns_resolver")
+	grpcService := r.PostForm.Get("grpc_service")
+	grpcTls := r.PostForm.Get("grpc_tls") == "on"
+	certExpiryWarn, _ := strconv.Atoi(r.PostForm.Get("cert_expiry_warn"))
+	groupName := strings.TrimSpace(r.PostForm.Get("group_name"))
+	parentId := utils.StringInt(r.PostForm.Get("parent_id"))
+	sloTarget, _ := strconv.ParseFloat(r.PostForm.Get("slo_target"), 64)
+	sloWindow, _ := strconv.Atoi(r.PostForm.Get("slo_window"))
+	sloLatency, _ := strconv.Atoi(r.PostForm.Get("slo_latency"))
+	sloBurnRate, _ := strconv.ParseFloat(r.PostForm.Get("slo_burn_rate"), 64)
+	hitsRetention, _ := strconv.Atoi(r.PostForm.Get("hits_retention"))
+	failuresRetention, _ := strconv.Atoi(r.PostForm.Get("failures_retention"))
 
 	service.Name = name
 	service.Domain = domain
@@ -235,8 +315,36 @@ func servicesUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	service.Type = checkType
 	service.Port = port
 	service.PostData = postData
+	service.Headers = headers
+	service.ContentType = contentType
+	service.SkipVerify = skipVerify
+	service.NoRedirects = noRedirects
 	service.Timeout = timeout
+	service.FailAfter = failAfter
+	service.RecoverAfter = recoverAfter
+	service.Retries = retries
+	service.Quorum = quorum
+	service.RetryDelay = retryDelay
 	service.Order = order
+	service.PacketCount = packetCount
+	service.MaxPacketLoss = maxPacketLoss
+	service.DnsRecord = dnsRecord
+	service.DnsResolver = dnsResolver
+	service.GrpcService = grpcService
+	service.GrpcTls = grpcTls
+	service.CertExpiryWarn = certExpiryWarn
+	service.GroupName = groupName
+	service.SloTarget = sloTarget
+	service.SloWindow = sloWindow
+	service.SloLatency = sloLatency
+	service.SloBurnRate = sloBurnRate
+	service.HitsRetention = hitsRetention
+	service.FailuresRetention = failuresRetention
+	if service.ValidParent(parentId) {
+		service.ParentId = parentId
+	} else {
+		utils.Log(2, fmt.Sprintf("Service %v cannot depend on service %v, it would create a dependency cycle", service.Name, parentId))
+	}
 
 	service.Update(true)
 	service.Check(true)
@@ -244,7 +352,7 @@ func servicesUpdateHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func servicesDeleteFailuresHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -254,20 +362,60 @@ func servicesDeleteFailuresHandler(w http.ResponseWriter, r *http.Request) {
 	executeResponse(w, r, "services.html", core.CoreApp.Services, "/services")
 }
 
+func servicesPauseHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsOperator(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	vars := mux.Vars(r)
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service != nil {
+		service.Pause()
+	}
+	http.Redirect(w, r, "/services", http.StatusSeeOther)
+}
+
+func servicesResumeHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsOperator(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	vars := mux.Vars(r)
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service != nil {
+		service.Resume()
+	}
+	http.Redirect(w, r, "/services", http.StatusSeeOther)
+}
+
 func checkinCreateUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 	vars := mux.Vars(r)
+	r.ParseForm()
 	interval := utils.StringInt(r.PostForm.Get("interval"))
-	serv := core.SelectService(utils.StringInt(vars["id"]))
-	service := serv
-	checkin := &types.Checkin{
-		Service:  service.Id,
-		Interval: interval,
-		Api:      utils.NewSHA1Hash(18),
+	grace := utils.StringInt(r.PostForm.Get("grace_period"))
+	service := core.SelectService(utils.StringInt(vars["id"]))
+	if service == nil {
+		http.Redirect(w, r, "/services", http.StatusSeeOther)
+		return
 	}
-	checkin.Create()
-	executeResponse(w, r, "service.html", service, "/services")
+	checkin := core.ReturnCheckin(&types.Checkin{
+		Service:     service.Id,
+		Interval:    interval,
+		GracePeriod: grace,
+		Schedule:    strings.TrimSpace(r.PostForm.Get("schedule")),
+		Timezone:    strings.TrimSpace(r.PostForm.Get("timezone")),
+		Api:         utils.NewSHA1Hash(18),
+	})
+	_, err := checkin.Create()
+	if err != nil {
+		utils.Log(3, fmt.Sprintf("Error creating checkin for service %v. %v", service.Name, err))
+		end := time.Now().UTC()
+		executeServiceView(w, r, service, end.Add((-24*7)*time.Hour).UTC(), end, fmt.Sprintf("Checkin could not be saved: %v", err))
+		return
+	}
+	executeResponse(w, r, "service.html", service, fmt.Sprintf("/service/%v", service.Id))
 }
@@ -25,10 +25,11 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 func settingsHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -36,7 +37,7 @@ func settingsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func saveSettingsHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -65,6 +66,9 @@ func saveSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	timezone := r.PostForm.Get("timezone")
 	timeFloat, _ := strconv.ParseFloat(timezone, 10)
 	app.Timezone = float32(timeFloat)
+	app.HitsRetention, _ = strconv.Atoi(r.PostForm.Get("hits_retention"))
+	app.FailuresRetention, _ = strconv.Atoi(r.PostForm.Get("failures_retention"))
+	app.LogsRetention, _ = strconv.Atoi(r.PostForm.Get("logs_retention"))
 
 	app.UseCdn = (r.PostForm.Get("enable_cdn") == "on")
 	core.CoreApp, _ = core.UpdateCore(app)
@@ -72,8 +76,42 @@ func saveSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	executeResponse(w, r, "settings.html", core.CoreApp, "/settings")
 }
 
+func saveAuthSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsAdmin(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	app := core.CoreApp
+	app.OidcIssuer = strings.TrimSpace(r.PostForm.Get("oidc_issuer"))
+	app.OidcClientId = strings.TrimSpace(r.PostForm.Get("oidc_client_id"))
+	// the client secret isn't shown in the form, leaving it empty keeps the saved secret
+	if secret := r.PostForm.Get("oidc_client_secret"); secret != "" {
+		app.OidcClientSecret = secret
+	}
+	app.OidcGroupsClaim = strings.TrimSpace(r.PostForm.Get("oidc_groups_claim"))
+	app.OidcAdminGroups = r.PostForm.Get("oidc_admin_groups")
+	app.OidcOperatorGroups = r.PostForm.Get("oidc_operator_groups")
+	app.LdapHost = strings.TrimSpace(r.PostForm.Get("ldap_host"))
+	app.LdapStartTLS = (r.PostForm.Get("ldap_start_tls") == "on")
+	app.LdapBindDn = strings.TrimSpace(r.PostForm.Get("ldap_bind_dn"))
+	// like the client secret, leaving the bind password empty keeps the saved password unless the bind DN is removed
+	if password := r.PostForm.Get("ldap_bind_password"); password != "" || app.LdapBindDn == "" {
+		app.LdapBindPassword = password
+	}
+	app.LdapBaseDn = strings.TrimSpace(r.PostForm.Get("ldap_base_dn"))
+	app.LdapFilter = strings.TrimSpace(r.PostForm.Get("ldap_filter"))
+	app.LdapAdminGroup = strings.TrimSpace(r.PostForm.Get("ldap_admin_group"))
+	app.LdapOperatorGroup = strings.TrimSpace(r.PostForm.Get("ldap_operator_group"))
+	app.DisablePasswords = (r.PostForm.Get("disable_passwords") == "on")
+	app.RequireTwoFactor = (r.PostForm.Get("require_two_factor") == "on")
+	app.TrustSsoTwoFactor = (r.PostForm.Get("trust_sso_two_factor") == "on")
+	core.CoreApp, _ = core.UpdateCore(app)
+	executeResponse(w, r, "settings.html", core.CoreApp, "/settings")
+}
+
 func saveSASSHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -90,7 +128,7 @@ func saveSASSHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func saveAssetsHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -110,7 +148,7 @@ func saveAssetsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func deleteAssetsHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -136,7 +174,7 @@ func parseGet(r *http.Request) url.Values {
 
 func saveNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	var err error
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -199,7 +237,7 @@ func saveNotificationHandler(w http.ResponseWriter, r *http.Request) {
 
 func testNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	var err error
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -0,0 +1,167 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hunterlong/statup/core"
+	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userPage is the data for the user page, NewToken is only set right after a token is created
+type userPage struct {
+	*core.User
+	NewToken string
+}
+
+// isSessionUser returns true if the HTTP request is authenticated by the user with the id
+func isSessionUser(r *http.Request, id int64) bool {
+	if !IsAuthenticated(r) {
+		return false
+	}
+	user := sessionUser(r)
+	return user != nil && user.Id == id
+}
+
+// accountTokensHandler will redirect to the user page of the logged in user, where the user's tokens are managed
+func accountTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	if user == nil || !IsAuthenticated(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/user/%v", user.Id), http.StatusSeeOther)
+}
+
+func createTokenHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !IsAdmin(r) && !isSessionUser(r, utils.StringInt(vars["id"])) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	user, err := core.SelectUser(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Redirect(w, r, "/users", http.StatusSeeOther)
+		return
+	}
+	token := core.ReturnToken(&types.Token{
+		User:   user.Id,
+		Name:   r.PostForm.Get("name"),
+		Scopes: strings.Join(r.PostForm["scopes"], ","),
+	})
+	days, _ := strconv.Atoi(r.PostForm.Get("expires"))
+	if days > 0 {
+		token.ExpiresAt = time.Now().UTC().AddDate(0, 0, days)
+	}
+	value, err := token.Create()
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("Issue creating token for user %v: %v", user.Username, err))
+	}
+	executeResponse(w, r, "user.html", userPage{User: user, NewToken: value}, nil)
+}
+
+func revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !validCSRF(r) || (!IsAdmin(r) && !isSessionUser(r, utils.StringInt(vars["id"]))) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	token, err := core.SelectToken(utils.StringInt(vars["token"]))
+	if err == nil && token.User == utils.StringInt(vars["id"]) {
+		token.Revoke()
+	}
+	http.Redirect(w, r, fmt.Sprintf("/user/%v", vars["id"]), http.StatusSeeOther)
+}
+
+func apiAllTokensHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !isAPIRole(r, types.RoleAdmin) && !isSessionUser(r, utils.StringInt(vars["id"])) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	user, err := core.SelectUser(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user.Tokens())
+}
+
+func apiCreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// tokens are managed by admins, or by their user when logged in, an API token can't create other tokens
+	if !isAPIRole(r, types.RoleAdmin) && !isSessionUser(r, utils.StringInt(vars["id"])) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	user, err := core.SelectUser(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	var token *types.Token
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&token)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	token.Id = 0
+	token.User = user.Id
+	token.LastUsed = time.Time{}
+	_, err = core.ReturnToken(token).Create()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+func apiRevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !isAPIRole(r, types.RoleAdmin) && !isSessionUser(r, utils.StringInt(vars["id"])) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	token, err := core.SelectToken(utils.StringInt(vars["token"]))
+	if err != nil || token.User != utils.StringInt(vars["id"]) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	err = token.Revoke()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	output := ApiResponse{
+		Object: "token",
+		Method: "delete",
+		Id:     token.Id,
+		Status: "success",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(output)
+}
@@ -0,0 +1,233 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+	"github.com/hunterlong/statup/core"
+	"github.com/hunterlong/statup/utils"
+	"html/template"
+	"image/png"
+	"net/http"
+	"time"
+)
+
+// twoFactorTimeout is how long a user has to enter their code after their password
+const twoFactorTimeout = 5 * time.Minute
+
+// twoFactorPage is the data for the two-factor authentication pages. QRCode and Secret are set while
+// the user is enabling it, RecoveryCodes are only set right after it's enabled.
+type twoFactorPage struct {
+	*core.User
+	Error         string
+	QRCode        template.URL
+	Secret        string
+	RecoveryCodes []string
+}
+
+// loginUser will login the user after their password or single sign-on login was checked. Users with two-factor
+// authentication are sent to enter their code first, and so are users that are required to enable it.
+func loginUser(w http.ResponseWriter, r *http.Request, session *sessions.Session, user *core.User, sso bool) {
+	if user.TotpEnabled || core.CoreApp.TwoFactorRequired(user, sso) {
+		session.Values["authenticated"] = false
+		session.Values["2fa_user_id"] = user.Id
+		session.Values["2fa_expires"] = time.Now().Add(twoFactorTimeout).Unix()
+		session.Values["2fa_sso"] = sso
+		session.Save(r, w)
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return
+	}
+	session.Values["authenticated"] = true
+	session.Values["user_id"] = user.Id
+	session.Values["sso"] = sso
+	session.Save(r, w)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// pendingUser returns the user that entered their password and still has to enter their code
+func pendingUser(r *http.Request) (*sessions.Session, *core.User) {
+	if Store == nil {
+		return nil, nil
+	}
+	session, err := Store.Get(r, COOKIE_KEY)
+	if err != nil {
+		return nil, nil
+	}
+	id, ok := session.Values["2fa_user_id"].(int64)
+	expires, _ := session.Values["2fa_expires"].(int64)
+	if !ok || time.Now().Unix() > expires {
+		return session, nil
+	}
+	user, err := core.SelectUser(id)
+	if err != nil {
+		return session, nil
+	}
+	return session, user
+}
+
+// twoFactorQRCode returns the user's TOTP key as a QR code image for the page
+func twoFactorQRCode(user *core.User, page *twoFactorPage) error {
+	key, err := user.TwoFactorKey()
+	if err != nil {
+		return err
+	}
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	page.QRCode = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+	page.Secret = key.Secret()
+	return nil
+}
+
+func twoFactorLoginHandler(w http.ResponseWriter, r *http.Request) {
+	_, user := pendingUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	page := &twoFactorPage{User: user}
+	if !user.TotpEnabled {
+		if err := twoFactorQRCode(user, page); err != nil {
+			utils.Log(3, fmt.Sprintf("Issue creating two-factor key for user %v: %v", user.Username, err))
+		}
+	}
+	executeResponse(w, r, "login_2fa.html", page, nil)
+}
+
+func twoFactorVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	session, user := pendingUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	code := r.PostForm.Get("code")
+	page := &twoFactorPage{User: user}
+	var err error
+	if user.TotpEnabled {
+		err = user.VerifyTwoFactor(code)
+	} else {
+		page.RecoveryCodes, err = user.EnableTwoFactor(code)
+	}
+	if err != nil {
+		utils.Log(2, fmt.Sprintf("Two-factor login for user %v failed: %v", user.Username, err))
+		page.Error = err.Error()
+		if !user.TotpEnabled {
+			twoFactorQRCode(user, page)
+		}
+		executeResponse(w, r, "login_2fa.html", page, nil)
+		return
+	}
+	sso, _ := session.Values["2fa_sso"].(bool)
+	delete(session.Values, "2fa_user_id")
+	delete(session.Values, "2fa_expires")
+	delete(session.Values, "2fa_sso")
+	session.Values["authenticated"] = true
+	session.Values["user_id"] = user.Id
+	session.Values["sso"] = sso
+	session.Save(r, w)
+	if page.RecoveryCodes != nil {
+		executeResponse(w, r, "login_2fa.html", page, nil)
+		return
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// accountHandler shows the logged in user's account page, where they manage two-factor authentication and link
+// their single sign-on account
+func accountHandler(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	if !IsAuthenticated(r) || user == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	page := &twoFactorPage{User: user}
+	if !user.TotpEnabled {
+		if err := twoFactorQRCode(user, page); err != nil {
+			utils.Log(3, fmt.Sprintf("Issue creating two-factor key for user %v: %v", user.Username, err))
+		}
+	}
+	executeResponse(w, r, "account.html", page, nil)
+}
+
+func enableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	if !IsAuthenticated(r) || user == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	page := &twoFactorPage{User: user}
+	codes, err := user.EnableTwoFactor(r.PostForm.Get("code"))
+	if err != nil {
+		page.Error = err.Error()
+		twoFactorQRCode(user, page)
+	}
+	page.RecoveryCodes = codes
+	executeResponse(w, r, "account.html", page, nil)
+}
+
+func disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	user := sessionUser(r)
+	if !IsAuthenticated(r) || user == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	page := &twoFactorPage{User: user}
+	if core.CoreApp.RequireTwoFactor {
+		page.Error = "Two-factor authentication is required for all users."
+		executeResponse(w, r, "account.html", page, nil)
+		return
+	}
+	if err := user.VerifyTwoFactor(r.PostForm.Get("code")); err != nil {
+		page.Error = err.Error()
+		executeResponse(w, r, "account.html", page, nil)
+		return
+	}
+	if err := user.DisableTwoFactor(); err != nil {
+		utils.Log(3, fmt.Sprintf("Issue disabling two-factor authentication for user %v: %v", user.Username, err))
+	}
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+func resetTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsAdmin(r) || !validCSRF(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	vars := mux.Vars(r)
+	user, err := core.SelectUser(utils.StringInt(vars["id"]))
+	if err != nil {
+		http.Redirect(w, r, "/users", http.StatusSeeOther)
+		return
+	}
+	if err := user.DisableTwoFactor(); err != nil {
+		utils.Log(3, fmt.Sprintf("Issue resetting two-factor authentication for user %v: %v", user.Username, err))
+	} else {
+		utils.Log(1, fmt.Sprintf("Two-factor authentication was reset for user %v", user.Username))
+	}
+	http.Redirect(w, r, fmt.Sprintf("/user/%v", user.Id), http.StatusSeeOther)
+}
@@ -26,7 +26,7 @@ import (
 )
 
 func usersHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -35,18 +35,19 @@ func usersHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func usersEditHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	// users can view their own page to manage their API tokens
+	if !IsAdmin(r) && !isSessionUser(r, int64(id)) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
-	vars := mux.Vars(r)
-	id, _ := strconv.Atoi(vars["id"])
 	user, _ := core.SelectUser(int64(id))
-	executeResponse(w, r, "user.html", user, nil)
+	executeResponse(w, r, "user.html", userPage{User: user}, nil)
 }
 
 func updateUserHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -63,6 +64,7 @@ func updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	user.Username = r.PostForm.Get("username")
 	user.Email = r.PostForm.Get("email")
 	user.Admin = (r.PostForm.Get("admin") == "on")
+	user.Role = r.PostForm.Get("role")
 	password := r.PostForm.Get("password")
 	if password != "##########" {
 		user.Password = utils.HashPassword(password)
@@ -73,7 +75,7 @@ func updateUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func createUserHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -88,6 +90,7 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 		Password: password,
 		Email:    email,
 		Admin:    (admin == "on"),
+		Role:     r.PostForm.Get("role"),
 	})
 	_, err := user.Create()
 	if err != nil {
@@ -98,7 +101,7 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func usersDeleteHandler(w http.ResponseWriter, r *http.Request) {
-	if !IsAuthenticated(r) {
+	if !IsAdmin(r) {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/hunterlong/statup/core/notifier"
 	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
 	"io/ioutil"
 	"net/http"
 	"time"
@@ -72,20 +73,22 @@ func (u *Discord) Select() *notifier.Notification {
 	return u.Notification
 }
 
-// OnFailure will trigger failing service
-func (u *Discord) OnFailure(s *types.Service, f *types.Failure) {
+// OnServiceDown will trigger when a service goes offline
+func (u *Discord) OnServiceDown(s *types.Service, f *types.Failure) {
 	msg := fmt.Sprintf(`{"content": "Your service '%v' is currently failing! Reason: %v"}`, s.Name, f.Issue)
 	u.AddQueue(msg)
-	u.Online = false
 }
 
-// OnSuccess will trigger successful service
-func (u *Discord) OnSuccess(s *types.Service) {
-	if !u.Online {
-		msg := fmt.Sprintf(`{"content": "Your service '%v' is back online!"}`, s.Name)
-		u.AddQueue(msg)
-	}
-	u.Online = true
+// OnServiceRecovered will trigger when a service is back online
+func (u *Discord) OnServiceRecovered(s *types.Service, downtime time.Duration) {
+	msg := fmt.Sprintf(`{"content": "Your service '%v' is back online after %v!"}`, s.Name, utils.DurationReadable(downtime))
+	u.AddQueue(msg)
+}
+
+// OnBudgetBurn will trigger when a service is using its error budget too fast
+func (u *Discord) OnBudgetBurn(s *types.Service, slo *types.SLO) {
+	msg := fmt.Sprintf(`{"content": "Your service '%v' is burning its error budget %vx faster than its %v%% SLO allows, %v%% of the budget remains."}`, s.Name, slo.BurnRate, slo.Target, slo.BudgetRemaining)
+	u.AddQueue(msg)
 }
 
 // OnSave triggers when this notifier has been saved
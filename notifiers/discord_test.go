@@ -17,6 +17,7 @@ package notifiers
 
 import (
 	"github.com/hunterlong/statup/core/notifier"
+	"github.com/hunterlong/statup/types"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -64,27 +65,19 @@ func TestDiscordNotifier(t *testing.T) {
 		assert.True(t, ok)
 	})
 
-	t.Run("Discord OnFailure", func(t *testing.T) {
-		discorder.OnFailure(TestService, TestFailure)
+	t.Run("Discord OnServiceDown", func(t *testing.T) {
+		discorder.OnServiceDown(TestService, TestFailure)
 		assert.Len(t, discorder.Queue, 1)
 	})
 
-	t.Run("Discord Check Offline", func(t *testing.T) {
-		assert.False(t, discorder.Online)
-	})
-
-	t.Run("Discord OnSuccess", func(t *testing.T) {
-		discorder.OnSuccess(TestService)
+	t.Run("Discord OnServiceRecovered", func(t *testing.T) {
+		discorder.OnServiceRecovered(TestService, 5*time.Minute)
 		assert.Len(t, discorder.Queue, 2)
 	})
 
-	t.Run("Discord Check Back Online", func(t *testing.T) {
-		assert.True(t, discorder.Online)
-	})
-
-	t.Run("Discord OnSuccess Again", func(t *testing.T) {
-		discorder.OnSuccess(TestService)
-		assert.Len(t, discorder.Queue, 2)
+	t.Run("Discord OnBudgetBurn", func(t *testing.T) {
+		discorder.OnBudgetBurn(TestService, &types.SLO{Target: 99.9, BurnRate: 20, BudgetRemaining: 85})
+		assert.Len(t, discorder.Queue, 3)
 	})
 
 	t.Run("Discord Send", func(t *testing.T) {
@@ -25,6 +25,7 @@ import (
 	"github.com/hunterlong/statup/utils"
 	"html/template"
 	"net/smtp"
+	"time"
 )
 
 const (
@@ -71,12 +72,14 @@ const (
                                 <tr>
                                     <td class="content-cell" style="box-sizing: border-box; font-family: Arial, 'Helvetica Neue', Helvetica, sans-serif; padding: 35px; word-break: break-word;">
                                         <h1 style="box-sizing: border-box; color: #2F3133; font-family: Arial, 'Helvetica Neue', Helvetica, sans-serif; font-size: 19px; font-weight: bold; margin-top: 0;" align="left">
-{{ .Name }} is {{ if .Online }}Online{{else}}Offline{{end}}!
+{{ if .SLO }}{{ .Name }} is Burning its Error Budget{{ else }}{{ .Name }} is {{ if .Online }}Online{{else}}Offline{{end}}!{{ end }}
 </h1>
                                         <p style="box-sizing: border-box; color: #74787E; font-family: Arial, 'Helvetica Neue', Helvetica, sans-serif; font-size: 16px; line-height: 1.5em; margin-top: 0;" align="left">
 
-{{ if .Online }}
-Your Statup service <a target="_blank" href="{{.Domain}}">{{.Name}}</a> is back online. This service has been triggered with a HTTP status code of '{{.LastStatusCode}}' and is currently online based on your requirements. Your service was reported online at {{.CreatedAt}}. </p>
+{{ if .SLO }}
+Your Statup service <a target="_blank" href="{{.Domain}}">{{.Name}}</a> is burning its error budget {{.SLO.BurnRate}}x faster than its {{.SLO.Target}}% SLO allows, {{.SLO.BudgetRemaining}}% of the error budget is remaining. </p>
+{{ else if .Online }}
+Your Statup service <a target="_blank" href="{{.Domain}}">{{.Name}}</a> is back online{{if .Downtime}} after being offline for {{.Downtime}}{{end}}. This service has been triggered with a HTTP status code of '{{.LastStatusCode}}' and is currently online based on your requirements. Your service was reported online at {{.CreatedAt}}. </p>
 {{ else }}
 Your Statup service <a target="_blank" href="{{.Domain}}">{{.Name}}</a> has been triggered with a HTTP status code of '{{.LastStatusCode}}' and is currently offline based on your requirements. This failure was created on {{.CreatedAt}}. </p>
 {{ end }}
@@ -178,32 +181,47 @@ type EmailOutgoing struct {
 	Sent     bool
 }
 
-// OnFailure will trigger failing service
-func (u *Email) OnFailure(s *types.Service, f *types.Failure) {
+// emailService is the data for the email TEMPLATE, the service with its outage duration or its SLO
+type emailService struct {
+	*types.Service
+	Downtime string
+	SLO      *types.SLO
+}
+
+// OnServiceDown will trigger when a service goes offline
+func (u *Email) OnServiceDown(s *types.Service, f *types.Failure) {
 	email := &EmailOutgoing{
 		To:       emailer.GetValue("var2"),
 		Subject:  fmt.Sprintf("Service %v is Failing", s.Name),
 		Template: TEMPLATE,
-		Data:     interface{}(s),
+		Data:     interface{}(emailService{Service: s}),
 		From:     emailer.GetValue("var1"),
 	}
 	u.AddQueue(email)
-	u.Online = false
 }
 
-// OnSuccess will trigger successful service
-func (u *Email) OnSuccess(s *types.Service) {
-	if !u.Online {
-		email := &EmailOutgoing{
-			To:       emailer.GetValue("var2"),
-			Subject:  fmt.Sprintf("Service %v is Back Online", s.Name),
-			Template: TEMPLATE,
-			Data:     interface{}(s),
-			From:     emailer.GetValue("var1"),
-		}
-		u.AddQueue(email)
+// OnServiceRecovered will trigger when a service is back online
+func (u *Email) OnServiceRecovered(s *types.Service, downtime time.Duration) {
+	email := &EmailOutgoing{
+		To:       emailer.GetValue("var2"),
+		Subject:  fmt.Sprintf("Service %v is Back Online", s.Name),
+		Template: TEMPLATE,
+		Data:     interface{}(emailService{Service: s, Downtime: utils.DurationReadable(downtime)}),
+		From:     emailer.GetValue("var1"),
 	}
-	u.Online = true
+	u.AddQueue(email)
+}
+
+// OnBudgetBurn will trigger when a service is using its error budget too fast
+func (u *Email) OnBudgetBurn(s *types.Service, slo *types.SLO) {
+	email := &EmailOutgoing{
+		To:       emailer.GetValue("var2"),
+		Subject:  fmt.Sprintf("Service %v is Burning its Error Budget", s.Name),
+		Template: TEMPLATE,
+		Data:     interface{}(emailService{Service: s, SLO: slo}),
+		From:     emailer.GetValue("var1"),
+	}
+	u.AddQueue(email)
 }
 
 func (u *Email) Select() *notifier.Notification {
@@ -18,6 +18,7 @@ package notifiers
 import (
 	"fmt"
 	"github.com/hunterlong/statup/core/notifier"
+	"github.com/hunterlong/statup/types"
 	"github.com/hunterlong/statup/utils"
 	"github.com/stretchr/testify/assert"
 	"os"
@@ -73,7 +74,7 @@ func TestEmailNotifier(t *testing.T) {
 			To:       emailer.GetValue("var2"),
 			Subject:  fmt.Sprintf("Service %v is Failing", TestService.Name),
 			Template: TEMPLATE,
-			Data:     TestService,
+			Data:     emailService{Service: TestService},
 			From:     emailer.GetValue("var1"),
 		}
 	})
@@ -100,27 +101,21 @@ func TestEmailNotifier(t *testing.T) {
 		assert.NotEmpty(t, testEmail.Source)
 	})
 
-	t.Run("Email OnFailure", func(t *testing.T) {
-		emailer.OnFailure(TestService, TestFailure)
+	t.Run("Email OnServiceDown", func(t *testing.T) {
+		emailer.OnServiceDown(TestService, TestFailure)
 		assert.Len(t, emailer.Queue, 1)
 	})
 
-	t.Run("Email Check Offline", func(t *testing.T) {
-		assert.False(t, emailer.Online)
-	})
-
-	t.Run("Email OnSuccess", func(t *testing.T) {
-		emailer.OnSuccess(TestService)
+	t.Run("Email OnServiceRecovered", func(t *testing.T) {
+		emailer.OnServiceRecovered(TestService, 5*time.Minute)
 		assert.Len(t, emailer.Queue, 2)
 	})
 
-	t.Run("Email Check Back Online", func(t *testing.T) {
-		assert.True(t, emailer.Online)
-	})
-
-	t.Run("Email OnSuccess Again", func(t *testing.T) {
-		emailer.OnSuccess(TestService)
-		assert.Len(t, emailer.Queue, 2)
+	t.Run("Email OnBudgetBurn", func(t *testing.T) {
+		emailer.OnBudgetBurn(TestService, &types.SLO{Target: 99.9, BurnRate: 20, BudgetRemaining: 85})
+		assert.Len(t, emailer.Queue, 3)
+		source := EmailTemplate(TEMPLATE, emailer.Queue[2].(*EmailOutgoing).Data)
+		assert.Contains(t, source, "burning its error budget 20x faster")
 	})
 
 	t.Run("Email Send", func(t *testing.T) {
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const (
@@ -76,20 +77,22 @@ func (u *LineNotify) Select() *notifier.Notification {
 	return u.Notification
 }
 
-// OnFailure will trigger failing service
-func (u *LineNotify) OnFailure(s *types.Service, f *types.Failure) {
+// OnServiceDown will trigger when a service goes offline
+func (u *LineNotify) OnServiceDown(s *types.Service, f *types.Failure) {
 	msg := fmt.Sprintf("Your service '%v' is currently offline!", s.Name)
 	u.AddQueue(msg)
-	u.Online = false
 }
 
-// OnSuccess will trigger successful service
-func (u *LineNotify) OnSuccess(s *types.Service) {
-	if !u.Online {
-		msg := fmt.Sprintf("Your service '%v' is back online!", s.Name)
-		u.AddQueue(msg)
-	}
-	u.Online = true
+// OnServiceRecovered will trigger when a service is back online
+func (u *LineNotify) OnServiceRecovered(s *types.Service, downtime time.Duration) {
+	msg := fmt.Sprintf("Your service '%v' is back online after %v!", s.Name, utils.DurationReadable(downtime))
+	u.AddQueue(msg)
+}
+
+// OnBudgetBurn will trigger when a service is using its error budget too fast
+func (u *LineNotify) OnBudgetBurn(s *types.Service, slo *types.SLO) {
+	msg := fmt.Sprintf("Your service '%v' is burning its error budget %vx faster than its %v%% SLO allows!", s.Name, slo.BurnRate, slo.Target)
+	u.AddQueue(msg)
 }
 
 // OnSave triggers when this notifier has been saved
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"github.com/hunterlong/statup/core/notifier"
 	"github.com/hunterlong/statup/types"
+	"github.com/hunterlong/statup/utils"
 	"io/ioutil"
 	"net/http"
 	"text/template"
@@ -30,7 +31,8 @@ import (
 const (
 	SLACK_METHOD     = "slack"
 	FAILING_TEMPLATE = `{ "attachments": [ { "fallback": "Service {{.Service.Name}} - is currently failing", "text": "<{{.Service.Domain}}|{{.Service.Name}}> - Your Statup service '{{.Service.Name}}' has just received a Failure notification with a HTTP Status code of {{.Service.LastStatusCode}}.", "fields": [ { "title": "Expected", "value": "{{.Service.Expected}}", "short": true }, { "title": "Status Code", "value": "{{.Service.LastStatusCode}}", "short": true } ], "color": "#FF0000", "thumb_url": "https://statup.io", "footer": "Statup", "footer_icon": "https://img.cjx.io/statuplogo32.png" } ] }`
-	SUCCESS_TEMPLATE = `{ "attachments": [ { "fallback": "Service {{.Service.Name}} - is now back online", "text": "<{{.Service.Domain}}|{{.Service.Name}}> - Your Statup service '{{.Service.Name}}' has just received a Failure notification.", "fields": [ { "title": "Issue", "value": "Awesome Project", "short": true }, { "title": "Status Code", "value": "{{.Service.LastStatusCode}}", "short": true } ], "color": "#00FF00", "thumb_url": "https://statup.io", "footer": "Statup", "footer_icon": "https://img.cjx.io/statuplogo32.png" } ] }`
+	SUCCESS_TEMPLATE = `{ "attachments": [ { "fallback": "Service {{.Service.Name}} - is now back online", "text": "<{{.Service.Domain}}|{{.Service.Name}}> - Your Statup service '{{.Service.Name}}' is back online after being offline for {{.Downtime}}.", "fields": [ { "title": "Downtime", "value": "{{.Downtime}}", "short": true }, { "title": "Status Code", "value": "{{.Service.LastStatusCode}}", "short": true } ], "color": "#00FF00", "thumb_url": "https://statup.io", "footer": "Statup", "footer_icon": "https://img.cjx.io/statuplogo32.png" } ] }`
+	BUDGET_TEMPLATE  = `{ "attachments": [ { "fallback": "Service {{.Service.Name}} - is burning its error budget", "text": "<{{.Service.Domain}}|{{.Service.Name}}> - Your Statup service '{{.Service.Name}}' is burning its error budget {{.SLO.BurnRate}}x faster than its {{.SLO.Target}}% SLO allows.", "fields": [ { "title": "Burn Rate", "value": "{{.SLO.BurnRate}}x", "short": true }, { "title": "Budget Remaining", "value": "{{.SLO.BudgetRemaining}}%", "short": true } ], "color": "#FFA500", "thumb_url": "https://statup.io", "footer": "Statup", "footer_icon": "https://img.cjx.io/statuplogo32.png" } ] }`
 	SLACK_TEXT       = `{"text":"{{.}}"}`
 )
 
@@ -71,6 +73,8 @@ type SlackMessage struct {
 	Service  *types.Service
 	Template string
 	Time     int64
+	Downtime string
+	SLO      *types.SLO
 }
 
 // DEFINE YOUR NOTIFICATION HERE.
@@ -112,28 +116,36 @@ func (u *Slack) OnTest() error {
 	return err
 }
 
-// OnFailure will trigger failing service
-func (u *Slack) OnFailure(s *types.Service, f *types.Failure) {
+// OnServiceDown will trigger when a service goes offline
+func (u *Slack) OnServiceDown(s *types.Service, f *types.Failure) {
 	message := SlackMessage{
 		Service:  s,
 		Template: FAILING_TEMPLATE,
 		Time:     time.Now().Unix(),
 	}
 	parseSlackMessage(FAILING_TEMPLATE, message)
-	u.Online = false
 }
 
-// OnSuccess will trigger successful service
-func (u *Slack) OnSuccess(s *types.Service) {
-	if !u.Online {
-		message := SlackMessage{
-			Service:  s,
-			Template: SUCCESS_TEMPLATE,
-			Time:     time.Now().Unix(),
-		}
-		parseSlackMessage(SUCCESS_TEMPLATE, message)
+// OnServiceRecovered will trigger when a service is back online
+func (u *Slack) OnServiceRecovered(s *types.Service, downtime time.Duration) {
+	message := SlackMessage{
+		Service:  s,
+		Template: SUCCESS_TEMPLATE,
+		Time:     time.Now().Unix(),
+		Downtime: utils.DurationReadable(downtime),
+	}
+	parseSlackMessage(SUCCESS_TEMPLATE, message)
+}
+
+// OnBudgetBurn will trigger when a service is using its error budget too fast
+func (u *Slack) OnBudgetBurn(s *types.Service, slo *types.SLO) {
+	message := SlackMessage{
+		Service:  s,
+		Template: BUDGET_TEMPLATE,
+		Time:     time.Now().Unix(),
+		SLO:      slo,
 	}
-	u.Online = true
+	parseSlackMessage(BUDGET_TEMPLATE, message)
 }
 
 // OnSave triggers when this notifier has been saved
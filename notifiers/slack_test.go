@@ -17,6 +17,7 @@ package notifiers
 
 import (
 	"github.com/hunterlong/statup/core/notifier"
+	"github.com/hunterlong/statup/types"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -72,33 +73,25 @@ func TestSlackNotifier(t *testing.T) {
 		assert.True(t, ok)
 	})
 
-	t.Run("Slack OnFailure", func(t *testing.T) {
-		slacker.OnFailure(TestService, TestFailure)
+	t.Run("Slack OnServiceDown", func(t *testing.T) {
+		slacker.OnServiceDown(TestService, TestFailure)
 		assert.Len(t, slacker.Queue, 2)
 	})
 
-	t.Run("Slack Check Offline", func(t *testing.T) {
-		assert.False(t, slacker.Online)
-	})
-
-	t.Run("Slack OnSuccess", func(t *testing.T) {
-		slacker.OnSuccess(TestService)
+	t.Run("Slack OnServiceRecovered", func(t *testing.T) {
+		slacker.OnServiceRecovered(TestService, 5*time.Minute)
 		assert.Len(t, slacker.Queue, 3)
 	})
 
-	t.Run("Slack Check Back Online", func(t *testing.T) {
-		assert.True(t, slacker.Online)
-	})
-
-	t.Run("Slack OnSuccess Again", func(t *testing.T) {
-		slacker.OnSuccess(TestService)
-		assert.Len(t, slacker.Queue, 3)
+	t.Run("Slack OnBudgetBurn", func(t *testing.T) {
+		slacker.OnBudgetBurn(TestService, &types.SLO{Target: 99.9, BurnRate: 20, BudgetRemaining: 85})
+		assert.Len(t, slacker.Queue, 4)
 	})
 
 	t.Run("Slack Send", func(t *testing.T) {
 		err := slacker.Send(slackMessage)
 		assert.Nil(t, err)
-		assert.Len(t, slacker.Queue, 3)
+		assert.Len(t, slacker.Queue, 4)
 	})
 
 	t.Run("Slack Queue", func(t *testing.T) {
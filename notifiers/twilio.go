@@ -108,20 +108,22 @@ func (u *twilio) Send(msg interface{}) error {
 	return nil
 }
 
-// OnFailure will trigger failing service
-func (u *twilio) OnFailure(s *types.Service, f *types.Failure) {
+// OnServiceDown will trigger when a service goes offline
+func (u *twilio) OnServiceDown(s *types.Service, f *types.Failure) {
 	msg := fmt.Sprintf("Your service '%v' is currently offline!", s.Name)
 	u.AddQueue(msg)
-	u.Online = false
 }
 
-// OnSuccess will trigger successful service
-func (u *twilio) OnSuccess(s *types.Service) {
-	if !u.Online {
-		msg := fmt.Sprintf("Your service '%v' is back online!", s.Name)
-		u.AddQueue(msg)
-	}
-	u.Online = true
+// OnServiceRecovered will trigger when a service is back online
+func (u *twilio) OnServiceRecovered(s *types.Service, downtime time.Duration) {
+	msg := fmt.Sprintf("Your service '%v' is back online after %v!", s.Name, utils.DurationReadable(downtime))
+	u.AddQueue(msg)
+}
+
+// OnBudgetBurn will trigger when a service is using its error budget too fast
+func (u *twilio) OnBudgetBurn(s *types.Service, slo *types.SLO) {
+	msg := fmt.Sprintf("Your service '%v' is burning its error budget %vx faster than its %v%% SLO allows!", s.Name, slo.BurnRate, slo.Target)
+	u.AddQueue(msg)
 }
 
 // OnSave triggers when this notifier has been saved
@@ -70,26 +70,13 @@ func TestTwilioNotifier(t *testing.T) {
 		assert.True(t, ok)
 	})
 
-	t.Run("Twilio OnFailure", func(t *testing.T) {
-		twilioNotifier.OnFailure(TestService, TestFailure)
+	t.Run("Twilio OnServiceDown", func(t *testing.T) {
+		twilioNotifier.OnServiceDown(TestService, TestFailure)
 		assert.Len(t, twilioNotifier.Queue, 1)
 	})
 
-	t.Run("Twilio Check Offline", func(t *testing.T) {
-		assert.False(t, twilioNotifier.Online)
-	})
-
-	t.Run("Twilio OnSuccess", func(t *testing.T) {
-		twilioNotifier.OnSuccess(TestService)
-		assert.Len(t, twilioNotifier.Queue, 2)
-	})
-
-	t.Run("Twilio Check Back Online", func(t *testing.T) {
-		assert.True(t, twilioNotifier.Online)
-	})
-
-	t.Run("Twilio OnSuccess Again", func(t *testing.T) {
-		twilioNotifier.OnSuccess(TestService)
+	t.Run("Twilio OnServiceRecovered", func(t *testing.T) {
+		twilioNotifier.OnServiceRecovered(TestService, 5*time.Minute)
 		assert.Len(t, twilioNotifier.Queue, 2)
 	})
 
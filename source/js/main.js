@@ -59,26 +59,46 @@ $('form').submit(function() {
     $(this).find('button[type=submit]').prop('disabled', true);
 });
 
+// serviceTypeFields contains the form fields that will be shown for each service check type
+var serviceTypeFields = {
+    '#service_check_type': ['http'],
+    '#post_data': ['http', 'udp'],
+    '#service_response': ['http', 'udp', 'dns'],
+    '#service_response_code': ['http'],
+    '#service_headers': ['http'],
+    '#service_content_type': ['http'],
+    '#service_skip_verify': ['http', 'grpc'],
+    '#service_cert_warn': ['http'],
+    '#service_port': ['tcp', 'udp', 'grpc'],
+    '#service_packet_count': ['icmp'],
+    '#service_packet_loss': ['icmp'],
+    '#service_dns_record': ['dns'],
+    '#service_dns_resolver': ['dns'],
+    '#service_grpc_service': ['grpc']
+};
+
+var servicePlaceholders = {
+    'http': 'https://google.com',
+    'tcp': 'localhost',
+    'udp': 'localhost',
+    'icmp': '192.168.1.1',
+    'dns': 'example.com',
+    'grpc': 'localhost'
+};
+
 $('select#service_type').on('change', function() {
     var selected = $('#service_type option:selected').val();
-    if (selected === 'tcp') {
-        $('#service_port').parent().parent().removeClass('d-none');
-        $('#service_check_type').parent().parent().addClass('d-none');
-        $('#service_url').attr('placeholder', 'localhost');
-
-        $('#post_data').parent().parent().addClass('d-none');
-        $('#service_response').parent().parent().addClass('d-none');
-        $('#service_response_code').parent().parent().addClass('d-none');
-    } else {
-        $('#post_data').parent().parent().removeClass('d-none');
-        $('#service_response').parent().parent().removeClass('d-none');
-        $('#service_response_code').parent().parent().removeClass('d-none');
-        $('#service_check_type').parent().parent().removeClass('d-none');
-        $('#service_url').attr('placeholder', 'https://google.com');
-
-        $('#service_port').parent().parent().addClass('d-none');
-    }
-
+    $.each(serviceTypeFields, function(field, types) {
+        var row = $(field).closest('.form-group');
+        if (types.indexOf(selected) !== -1) {
+            row.removeClass('d-none');
+        } else {
+            row.addClass('d-none');
+        }
+    });
+    $('.service-http-option').toggleClass('d-none', selected !== 'http');
+    $('.service-grpc-option').toggleClass('d-none', selected !== 'grpc');
+    $('#service_url').attr('placeholder', servicePlaceholders[selected]);
 });
 
 function AjaxChart(chart, service, start=0, end=9999999999, group="hour") {
@@ -97,7 +117,7 @@ function AjaxChart(chart, service, start=0, end=9999999999, group="hour") {
 
 $('select#service_check_type').on('change', function() {
     var selected = $('#service_check_type option:selected').val();
-    if (selected === 'POST') {
+    if (selected !== 'GET' && selected !== 'HEAD') {
         $('#post_data').parent().parent().removeClass('d-none');
     } else {
         $('#post_data').parent().parent().addClass('d-none');
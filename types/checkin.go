@@ -23,14 +23,31 @@ type Checkin struct {
 	Id               int64     `gorm:"primary_key;column:id"`
 	Service          int64     `gorm:"index;column:service"`
 	Interval         int64     `gorm:"column:check_interval"`
+	GracePeriod      int64     `gorm:"column:grace_period" json:"grace_period"`
+	Schedule         string    `gorm:"column:schedule" json:"schedule"`
+	Timezone         string    `gorm:"column:timezone" json:"timezone"`
 	Api              string    `gorm:"column:api"`
 	CreatedAt        time.Time `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt        time.Time `gorm:"column:updated_at" json:"updated_at"`
 	Hits             int64     `json:"hits"`
 	Last             time.Time `json:"last"`
+	Failing          bool      `gorm:"-" json:"failing"`
+	Started          time.Time `gorm:"-" json:"started"`
+	Running          chan bool `gorm:"-" json:"-"`
 	CheckinInterface `json:"-"`
 }
 
+// CheckinHit is a single ping received for a check-in. A job can send a 'start' ping when it begins so its
+// runtime is recorded on the 'success' or 'fail' ping when it finishes.
+type CheckinHit struct {
+	Id        int64     `gorm:"primary_key;column:id" json:"id"`
+	Checkin   int64     `gorm:"index;column:checkin" json:"checkin"`
+	Status    string    `gorm:"column:status" json:"status"`
+	Duration  float64   `gorm:"column:duration" json:"duration"`
+	Message   string    `gorm:"column:message" json:"message,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
 type CheckinInterface interface {
 	// Database functions
 	Create() (int64, error)
@@ -39,3 +56,28 @@ type CheckinInterface interface {
 	Ago() string
 	Receivehit()
 }
+
+// Start will create a channel for the check-in's expiration go routine
+func (c *Checkin) Start() {
+	c.Running = make(chan bool)
+}
+
+// Close will stop the check-in's expiration go routine
+func (c *Checkin) Close() {
+	if c.IsRunning() {
+		close(c.Running)
+	}
+}
+
+// IsRunning returns true if the check-in's go routine is running
+func (c *Checkin) IsRunning() bool {
+	if c.Running == nil {
+		return false
+	}
+	select {
+	case <-c.Running:
+		return false
+	default:
+		return true
+	}
+}
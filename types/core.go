@@ -25,28 +25,48 @@ type AllNotifiers interface{}
 // will be saved into 1 row in the 'core' table. You can use the core.CoreApp
 // global variable to interact with the attributes to the application, such as services.
 type Core struct {
-	Name          string             `gorm:"not null;column:name" json:"name"`
-	Description   string             `gorm:"not null;column:description" json:"description,omitempty"`
-	Config        string             `gorm:"column:config" json:"-"`
-	ApiKey        string             `gorm:"column:api_key" json:"-"`
-	ApiSecret     string             `gorm:"column:api_secret" json:"-"`
-	Style         string             `gorm:"not null;column:style" json:"style,omitempty"`
-	Footer        string             `gorm:"not null;column:footer" json:"footer,omitempty"`
-	Domain        string             `gorm:"not null;column:domain" json:"domain,omitempty"`
-	Version       string             `gorm:"column:version" json:"version"`
-	MigrationId   int64              `gorm:"column:migration_id" json:"migration_id,omitempty"`
-	UseCdn        bool               `gorm:"column:use_cdn;default:false" json:"using_cdn,omitempty"`
-	Timezone      float32            `gorm:"column:timezone;default:-8.0" json:"timezone,omitempty"`
-	CreatedAt     time.Time          `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt     time.Time          `gorm:"column:updated_at" json:"updated_at"`
-	DbConnection  string             `gorm:"-" json:"database"`
-	Started       time.Time          `gorm:"-" json:"started_on"`
-	Services      []ServiceInterface `gorm:"-" json:"services,omitempty"`
-	Plugins       []Info             `gorm:"-" json:"-"`
-	Repos         []PluginJSON       `gorm:"-" json:"-"`
-	AllPlugins    []PluginActions    `gorm:"-" json:"-"`
-	Notifications []AllNotifiers     `gorm:"-" json:"-"`
-	CoreInterface `gorm:"-" json:"-"`
+	Name               string             `gorm:"not null;column:name" json:"name"`
+	Description        string             `gorm:"not null;column:description" json:"description,omitempty"`
+	Config             string             `gorm:"column:config" json:"-"`
+	ApiKey             string             `gorm:"column:api_key" json:"-"`
+	ApiSecret          string             `gorm:"column:api_secret" json:"-"`
+	Style              string             `gorm:"not null;column:style" json:"style,omitempty"`
+	Footer             string             `gorm:"not null;column:footer" json:"footer,omitempty"`
+	Domain             string             `gorm:"not null;column:domain" json:"domain,omitempty"`
+	Version            string             `gorm:"column:version" json:"version"`
+	MigrationId        int64              `gorm:"column:migration_id" json:"migration_id,omitempty"`
+	UseCdn             bool               `gorm:"column:use_cdn;default:false" json:"using_cdn,omitempty"`
+	Timezone           float32            `gorm:"column:timezone;default:-8.0" json:"timezone,omitempty"`
+	HitsRetention      int                `gorm:"column:hits_retention;default:90" json:"hits_retention"`
+	FailuresRetention  int                `gorm:"column:failures_retention;default:90" json:"failures_retention"`
+	LogsRetention      int                `gorm:"column:logs_retention;default:30" json:"logs_retention"`
+	OidcIssuer         string             `gorm:"column:oidc_issuer" json:"-"`
+	OidcClientId       string             `gorm:"column:oidc_client_id" json:"-"`
+	OidcClientSecret   string             `gorm:"column:oidc_client_secret" json:"-"`
+	OidcGroupsClaim    string             `gorm:"column:oidc_groups_claim" json:"-"`
+	OidcAdminGroups    string             `gorm:"column:oidc_admin_groups" json:"-"`
+	OidcOperatorGroups string             `gorm:"column:oidc_operator_groups" json:"-"`
+	LdapHost           string             `gorm:"column:ldap_host" json:"-"`
+	LdapStartTLS       bool               `gorm:"column:ldap_start_tls;default:false" json:"-"`
+	LdapBindDn         string             `gorm:"column:ldap_bind_dn" json:"-"`
+	LdapBindPassword   string             `gorm:"column:ldap_bind_password" json:"-"`
+	LdapBaseDn         string             `gorm:"column:ldap_base_dn" json:"-"`
+	LdapFilter         string             `gorm:"column:ldap_filter" json:"-"`
+	LdapAdminGroup     string             `gorm:"column:ldap_admin_group" json:"-"`
+	LdapOperatorGroup  string             `gorm:"column:ldap_operator_group" json:"-"`
+	DisablePasswords   bool               `gorm:"column:disable_passwords;default:false" json:"-"`
+	RequireTwoFactor   bool               `gorm:"column:require_two_factor;default:false" json:"-"`
+	TrustSsoTwoFactor  bool               `gorm:"column:trust_sso_two_factor;default:false" json:"-"`
+	CreatedAt          time.Time          `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt          time.Time          `gorm:"column:updated_at" json:"updated_at"`
+	DbConnection       string             `gorm:"-" json:"database"`
+	Started            time.Time          `gorm:"-" json:"started_on"`
+	Services           []ServiceInterface `gorm:"-" json:"services,omitempty"`
+	Plugins            []Info             `gorm:"-" json:"-"`
+	Repos              []PluginJSON       `gorm:"-" json:"-"`
+	AllPlugins         []PluginActions    `gorm:"-" json:"-"`
+	Notifications      []AllNotifiers     `gorm:"-" json:"-"`
+	CoreInterface      `gorm:"-" json:"-"`
 }
 
 type CoreInterface interface {
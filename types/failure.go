@@ -25,6 +25,10 @@ type Failure struct {
 	Id               int64     `gorm:"primary_key;column:id" json:"id"`
 	Issue            string    `gorm:"column:issue" json:"issue"`
 	Method           string    `gorm:"column:method" json:"method,omitempty"`
+	Attempts         int       `gorm:"default:1;column:attempts" json:"attempts"`
+	Location         string    `gorm:"column:location" json:"location,omitempty"`
+	Maintenance      bool      `gorm:"column:maintenance;type:boolean;default:false" json:"maintenance"`
+	DependencyDown   bool      `gorm:"column:dependency_down;type:boolean;default:false" json:"dependency_down"`
 	Service          int64     `gorm:"index;column:service" json:"-"`
 	CreatedAt        time.Time `gorm:"column:created_at" json:"created_at"`
 	FailureInterface `gorm:"-" json:"-"`
@@ -0,0 +1,53 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"time"
+)
+
+// IncidentStatuses are the statuses an Incident can have, in the order they usually happen
+var IncidentStatuses = []string{"investigating", "identified", "monitoring", "resolved"}
+
+// Incident is a human written report about an outage for one or more services. Each status change is
+// saved as an IncidentUpdate to create a timeline on the status page.
+type Incident struct {
+	Id             int64             `gorm:"primary_key;column:id" json:"id"`
+	Title          string            `gorm:"column:title" json:"title"`
+	Status         string            `gorm:"column:status" json:"status"`
+	AcknowledgedBy string            `gorm:"column:acknowledged_by" json:"acknowledged_by,omitempty"`
+	AcknowledgedAt time.Time         `gorm:"column:acknowledged_at" json:"acknowledged_at"`
+	CreatedAt      time.Time         `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time         `gorm:"column:updated_at" json:"updated_at"`
+	Services       []int64           `gorm:"-" json:"services"`
+	Updates        []*IncidentUpdate `gorm:"-" json:"updates"`
+}
+
+// IncidentUpdate is a message posted to an Incident's timeline
+type IncidentUpdate struct {
+	Id        int64     `gorm:"primary_key;column:id" json:"id"`
+	Incident  int64     `gorm:"index;column:incident" json:"incident"`
+	Status    string    `gorm:"column:status" json:"status"`
+	Message   string    `gorm:"column:message" json:"message"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// IncidentService links an Incident to an affected Service
+type IncidentService struct {
+	Id       int64 `gorm:"primary_key;column:id"`
+	Incident int64 `gorm:"index;column:incident"`
+	Service  int64 `gorm:"index;column:service"`
+}
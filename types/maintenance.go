@@ -0,0 +1,34 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"time"
+)
+
+// Maintenance is a scheduled window where a service, or every service if Service is 0, is expected to fail.
+// Failures during a window are still saved but will not send notifications or count against uptime.
+// A window can happen once, or repeat every day or week from its Start.
+type Maintenance struct {
+	Id        int64     `gorm:"primary_key;column:id" json:"id"`
+	Service   int64     `gorm:"index;column:service" json:"service"`
+	Message   string    `gorm:"column:message" json:"message"`
+	Start     time.Time `gorm:"column:start_time" json:"start"`
+	End       time.Time `gorm:"column:end_time" json:"end"`
+	Recurring string    `gorm:"column:recurring" json:"recurring"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
@@ -0,0 +1,32 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"time"
+)
+
+// Outage is a period a service was failing, computed from its check history. An outage starts with the first
+// failure after a successful check and ends with the next successful check.
+type Outage struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Ongoing  bool      `json:"ongoing"`
+	Duration int64     `json:"duration"`
+	Failures uint64    `json:"failures"`
+	Reason   string    `json:"reason"`
+	Issue    string    `json:"issue"`
+}
@@ -0,0 +1,37 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import "time"
+
+// ProbeResult is sent from a remote probe to the main Statup instance after each service check
+type ProbeResult struct {
+	Location     string  `json:"location"`
+	Online       bool    `json:"online"`
+	Latency      float64 `json:"latency"`
+	DnsLookup    float64 `json:"dns_lookup,omitempty"`
+	ConnectTime  float64 `json:"connect_time,omitempty"`
+	TLSHandshake float64 `json:"tls_handshake,omitempty"`
+	FirstByte    float64 `json:"first_byte,omitempty"`
+	Issue        string  `json:"issue,omitempty"`
+	Attempts     int     `json:"attempts,omitempty"`
+}
+
+// LocationStatus is the last status reported from a location, it's only counted towards the quorum while it's recent
+type LocationStatus struct {
+	Online   bool      `json:"online"`
+	LastSeen time.Time `json:"last_seen"`
+}
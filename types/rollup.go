@@ -0,0 +1,38 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"time"
+)
+
+// Rollup is a summary of a service's hits and failures for an hour or a day. Rollups are saved in the
+// 'hourly_rollups' and 'daily_rollups' tables so long term history is kept after old hits and failures are deleted.
+type Rollup struct {
+	Id             int64     `gorm:"primary_key;column:id" json:"-"`
+	Service        int64     `gorm:"index;column:service" json:"service"`
+	Start          time.Time `gorm:"index;column:start_time" json:"start"`
+	Hits           int64     `gorm:"column:hits" json:"hits"`
+	Failures       int64     `gorm:"column:failures" json:"failures"`
+	UptimeHits     int64     `gorm:"column:uptime_hits" json:"-"`
+	UptimeFailures int64     `gorm:"column:uptime_failures" json:"-"`
+	AvgLatency     float64   `gorm:"column:avg_latency" json:"avg_latency"`
+	MinLatency     float64   `gorm:"column:min_latency" json:"min_latency"`
+	MaxLatency     float64   `gorm:"column:max_latency" json:"max_latency"`
+	P50Latency     float64   `gorm:"column:p50_latency" json:"p50_latency"`
+	P95Latency     float64   `gorm:"column:p95_latency" json:"p95_latency"`
+	P99Latency     float64   `gorm:"column:p99_latency" json:"p99_latency"`
+}
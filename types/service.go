@@ -20,33 +20,74 @@ import (
 )
 
 type Service struct {
-	Id             int64         `gorm:"primary_key;column:id" json:"id"`
-	Name           string        `gorm:"column:name" json:"name"`
-	Domain         string        `gorm:"column:domain" json:"domain"`
-	Expected       string        `gorm:"not null;column:expected" json:"expected"`
-	ExpectedStatus int           `gorm:"default:200;column:expected_status" json:"expected_status"`
-	Interval       int           `gorm:"default:30;column:check_interval" json:"check_interval"`
-	Type           string        `gorm:"column:check_type" json:"type"`
-	Method         string        `gorm:"column:method" json:"method"`
-	PostData       string        `gorm:"not null;column:post_data" json:"post_data"`
-	Port           int           `gorm:"not null;column:port" json:"port"`
-	Timeout        int           `gorm:"default:30;column:timeout" json:"timeout"`
-	Order          int           `gorm:"default:0;column:order_id" json:"order_id"`
-	CreatedAt      time.Time     `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt      time.Time     `gorm:"column:updated_at" json:"updated_at"`
-	Online         bool          `gorm:"-" json:"online"`
-	Latency        float64       `gorm:"-" json:"latency"`
-	Online24Hours  float32       `gorm:"-" json:"24_hours_online"`
-	AvgResponse    string        `gorm:"-" json:"avg_response"`
-	Running        chan bool     `gorm:"-" json:"-"`
-	Checkpoint     time.Time     `gorm:"-" json:"-"`
-	SleepDuration  time.Duration `gorm:"-" json:"-"`
-	LastResponse   string        `gorm:"-" json:"-"`
-	LastStatusCode int           `gorm:"-" json:"status_code"`
-	LastOnline     time.Time     `gorm:"-" json:"last_online"`
-	DnsLookup      float64       `gorm:"-" json:"dns_lookup_time"`
-	Failures       []interface{} `gorm:"-" json:"failures,omitempty"`
-	Checkins       []*Checkin    `gorm:"-" json:"checkins,omitempty"`
+	Id                int64         `gorm:"primary_key;column:id" json:"id"`
+	Name              string        `gorm:"column:name" json:"name"`
+	Domain            string        `gorm:"column:domain" json:"domain"`
+	Expected          string        `gorm:"not null;column:expected" json:"expected"`
+	ExpectedStatus    int           `gorm:"default:200;column:expected_status" json:"expected_status"`
+	Interval          int           `gorm:"default:30;column:check_interval" json:"check_interval"`
+	Type              string        `gorm:"column:check_type" json:"type"`
+	Method            string        `gorm:"column:method" json:"method"`
+	PostData          string        `gorm:"not null;column:post_data" json:"post_data"`
+	Headers           string        `gorm:"column:headers" json:"headers"`
+	ContentType       string        `gorm:"column:content_type" json:"content_type"`
+	SkipVerify        bool          `gorm:"column:skip_verify;type:boolean;default:false" json:"skip_verify"`
+	NoRedirects       bool          `gorm:"column:no_redirects;type:boolean;default:false" json:"no_redirects"`
+	Port              int           `gorm:"not null;column:port" json:"port"`
+	Timeout           int           `gorm:"default:30;column:timeout" json:"timeout"`
+	FailAfter         int           `gorm:"default:1;column:fail_after" json:"fail_after"`
+	RecoverAfter      int           `gorm:"default:1;column:recover_after" json:"recover_after"`
+	Quorum            int           `gorm:"default:1;column:quorum" json:"quorum"`
+	Retries           int           `gorm:"default:0;column:retries" json:"retries"`
+	RetryDelay        int           `gorm:"default:500;column:retry_delay" json:"retry_delay"`
+	PacketCount       int           `gorm:"default:3;column:packet_count" json:"packet_count"`
+	MaxPacketLoss     int           `gorm:"default:0;column:max_packet_loss" json:"max_packet_loss"`
+	DnsRecord         string        `gorm:"column:dns_record" json:"dns_record"`
+	DnsResolver       string        `gorm:"column:dns_resolver" json:"dns_resolver"`
+	GrpcService       string        `gorm:"column:grpc_service" json:"grpc_service"`
+	GrpcTls           bool          `gorm:"column:grpc_tls;type:boolean;default:false" json:"grpc_tls"`
+	CertExpiryWarn    int           `gorm:"default:0;column:cert_expiry_warn" json:"cert_expiry_warn"`
+	Order             int           `gorm:"default:0;column:order_id" json:"order_id"`
+	GroupName         string        `gorm:"column:group_name" json:"group_name"`
+	ParentId          int64         `gorm:"default:0;column:parent_id" json:"parent_id"`
+	SloTarget         float64       `gorm:"default:0;column:slo_target" json:"slo_target"`
+	SloWindow         int           `gorm:"default:30;column:slo_window" json:"slo_window"`
+	SloLatency        int           `gorm:"default:0;column:slo_latency" json:"slo_latency"`
+	SloBurnRate       float64       `gorm:"default:14.4;column:slo_burn_rate" json:"slo_burn_rate"`
+	HitsRetention     int           `gorm:"default:0;column:hits_retention" json:"hits_retention"`
+	FailuresRetention int           `gorm:"default:0;column:failures_retention" json:"failures_retention"`
+	Paused            bool          `gorm:"column:paused;type:boolean;default:false" json:"paused"`
+	CreatedAt         time.Time     `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt         time.Time     `gorm:"column:updated_at" json:"updated_at"`
+	Online            bool          `gorm:"-" json:"online"`
+	Latency           float64       `gorm:"-" json:"latency"`
+	Online24Hours     float32       `gorm:"-" json:"24_hours_online"`
+	AvgResponse       string        `gorm:"-" json:"avg_response"`
+	Running           chan bool     `gorm:"-" json:"-"`
+	Checkpoint        time.Time     `gorm:"-" json:"-"`
+	SleepDuration     time.Duration `gorm:"-" json:"-"`
+	LastResponse      string        `gorm:"-" json:"-"`
+	LastStatusCode    int           `gorm:"-" json:"status_code"`
+	LastOnline        time.Time     `gorm:"-" json:"last_online"`
+	FailureStreak     int           `gorm:"-" json:"failure_streak"`
+	SuccessStreak     int           `gorm:"-" json:"success_streak"`
+	DownSince         time.Time     `gorm:"-" json:"-"`
+	DownNotified      bool          `gorm:"-" json:"-"`
+	BudgetAlerted     bool          `gorm:"-" json:"-"`
+	Attempt           int           `gorm:"-" json:"-"`
+	Retrying          bool          `gorm:"-" json:"-"`
+	DnsLookup         float64       `gorm:"-" json:"dns_lookup_time"`
+	ConnectTime       float64       `gorm:"-" json:"connect_time"`
+	TLSHandshake      float64       `gorm:"-" json:"tls_handshake_time"`
+	FirstByte         float64       `gorm:"-" json:"first_byte_time"`
+	CertExpiry        time.Time     `gorm:"-" json:"cert_expiry,omitempty"`
+	CertIssuer        string        `gorm:"-" json:"cert_issuer,omitempty"`
+	CertDNSNames      []string      `gorm:"-" json:"cert_dns_names,omitempty"`
+	Failures          []interface{} `gorm:"-" json:"failures,omitempty"`
+	Checkins          []*Checkin    `gorm:"-" json:"checkins,omitempty"`
+
+	// Locations holds the last status reported from each probe location, the main instance is ""
+	Locations map[string]*LocationStatus `gorm:"-" json:"locations,omitempty"`
 }
 
 type ServiceInterface interface {
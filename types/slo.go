@@ -0,0 +1,34 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+// SLO is a service's availability and latency objective over a rolling window of days. The error budget is
+// the percent of checks allowed to fail, and the burn rate is how fast the budget is being used over the
+// last hour, a burn rate of 1 would use the entire budget exactly by the end of the window.
+type SLO struct {
+	Target          float64 `json:"target"`
+	Window          int     `json:"window_days"`
+	Hits            uint64  `json:"hits"`
+	Failures        uint64  `json:"failures"`
+	Uptime          float64 `json:"uptime"`
+	ErrorBudget     float64 `json:"error_budget"`
+	BudgetRemaining float64 `json:"budget_remaining"`
+	BurnRate        float64 `json:"burn_rate"`
+	BurnRateAlert   float64 `json:"burn_rate_alert"`
+	LatencyTarget   int     `json:"latency_target"`
+	LatencyP95      float64 `json:"latency_p95"`
+	LatencyMet      bool    `json:"latency_met"`
+}
@@ -0,0 +1,46 @@
+// Statup
+// Copyright (C) 2018.  Hunter Long and the project contributors
+// Written by Hunter Long <info@socialeck.com> and the project contributors
+//
+// https://github.com/hunterlong/statup
+//
+// The licenses for most software and other practical works are designed
+// to take away your freedom to share and change the works.  By contrast,
+// the GNU General Public License is intended to guarantee your freedom to
+// share and change all versions of a program--to make sure it remains free
+// software for all its users.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"time"
+)
+
+// Token scopes, a Token can only be used for the API endpoints its scopes allow
+const (
+	ScopeRead          = "read"
+	ScopeServicesWrite = "services:write"
+	ScopeMetrics       = "metrics"
+	ScopeProbesWrite   = "probes:write"
+)
+
+// TokenScopes are the scopes a Token can have
+var TokenScopes = []string{ScopeRead, ScopeServicesWrite, ScopeMetrics, ScopeProbesWrite}
+
+// Token is a named API token for a User. Only a hash of the token is saved, the token itself
+// is only returned once when it's created.
+type Token struct {
+	Id        int64     `gorm:"primary_key;column:id" json:"id"`
+	User      int64     `gorm:"index;column:user_id" json:"user_id"`
+	Name      string    `gorm:"column:name" json:"name"`
+	Hash      string    `gorm:"type:varchar(64);unique_index;column:hash" json:"-"`
+	Prefix    string    `gorm:"column:prefix" json:"prefix"`
+	Scopes    string    `gorm:"column:scopes" json:"scopes"`
+	ExpiresAt time.Time `gorm:"column:expires_at" json:"expires_at"`
+	LastUsed  time.Time `gorm:"column:last_used" json:"last_used"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	Value     string    `gorm:"-" json:"token,omitempty"`
+}
@@ -23,10 +23,16 @@ import (
 
 // Hit struct is a 'successful' ping or web response entry for a service.
 type Hit struct {
-	Id        int64     `gorm:"primary_key;column:id"`
-	Service   int64     `gorm:"column:service"`
-	Latency   float64   `gorm:"column:latency"`
-	CreatedAt time.Time `gorm:"column:created_at"`
+	Id           int64     `gorm:"primary_key;column:id"`
+	Service      int64     `gorm:"column:service"`
+	Latency      float64   `gorm:"column:latency"`
+	DnsLookup    float64   `gorm:"column:dns_lookup;default:0"`
+	ConnectTime  float64   `gorm:"column:connect_time;default:0"`
+	TLSHandshake float64   `gorm:"column:tls_handshake;default:0"`
+	FirstByte    float64   `gorm:"column:first_byte;default:0"`
+	Location     string    `gorm:"column:location"`
+	Maintenance  bool      `gorm:"column:maintenance;type:boolean;default:false"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
 }
 
 // DbConfig struct is used for the database connection and creates the 'config.yml' file
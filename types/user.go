@@ -19,6 +19,25 @@ import (
 	"time"
 )
 
+// User roles, each role can do everything the roles before it can. Viewers can see the dashboard and
+// services, operators can also acknowledge incidents and pause services, admins can manage everything.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// UserRoles are the roles a User can have, from the least to the most access
+var UserRoles = []string{RoleViewer, RoleOperator, RoleAdmin}
+
+// User sources, where a User was created. Local users login with their password, single sign-on and
+// LDAP users are created on their first login and found by their AuthSubject after that.
+const (
+	SourceLocal = "local"
+	SourceLDAP  = "ldap"
+	SourceOIDC  = "oidc"
+)
+
 type User struct {
 	Id            int64     `gorm:"primary_key;column:id" json:"id"`
 	Username      string    `gorm:"type:varchar(100);unique;column:username;" json:"username"`
@@ -27,6 +46,12 @@ type User struct {
 	ApiKey        string    `gorm:"column:api_key" json:"api_key"`
 	ApiSecret     string    `gorm:"column:api_secret" json:"-"`
 	Admin         bool      `gorm:"column:administrator" json:"admin"`
+	Role          string    `gorm:"column:role" json:"role"`
+	TotpSecret    string    `gorm:"column:totp_secret" json:"-"`
+	TotpEnabled   bool      `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	RecoveryCodes string    `gorm:"type:text;column:recovery_codes" json:"-"`
+	AuthSource    string    `gorm:"column:auth_source" json:"auth_source"`
+	AuthSubject   string    `gorm:"index;column:auth_subject" json:"-"`
 	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at"`
 	UserInterface `gorm:"-" json:"-"`